@@ -0,0 +1,86 @@
+package gocoding
+
+import (
+	"reflect"
+)
+
+// ChainEncoding combines encodings; each is tried in order, and the first
+// encoder that is not nil is used
+func ChainEncoding(encodings ...Encoding) Encoding {
+	return func(marshaller Marshaller, theType reflect.Type) Encoder {
+		for _, encoding := range encodings {
+			if encoder := encoding(marshaller, theType); encoder != nil {
+				return encoder
+			}
+		}
+		return nil
+	}
+}
+
+// ChainDecoding combines decodings; each is tried in order, and the first
+// decoder that is not nil is used
+func ChainDecoding(decodings ...Decoding) Decoding {
+	return func(unmarshaller Unmarshaller, theType reflect.Type) Decoder {
+		for _, decoding := range decodings {
+			if decoder := decoding(unmarshaller, theType); decoder != nil {
+				return decoder
+			}
+		}
+		return nil
+	}
+}
+
+// EncodingLink is a link of a chain of encodings that can fall back to the
+// rest of the chain, for instance for values that cannot receive the methods
+// of the interface it encodes
+type EncodingLink func(marshaller Marshaller, theType reflect.Type, next Encoding) Encoder
+
+// DecodingLink is a link of a chain of decodings that can fall back to the
+// rest of the chain
+type DecodingLink func(unmarshaller Unmarshaller, theType reflect.Type, next Decoding) Decoder
+
+// ChainEncodingLinks combines links; each is called with the rest of the chain
+func ChainEncodingLinks(links ...EncodingLink) Encoding {
+	if len(links) == 0 {
+		return func(Marshaller, reflect.Type) Encoder { return nil }
+	}
+
+	link, next := links[0], ChainEncodingLinks(links[1:]...)
+	return func(marshaller Marshaller, theType reflect.Type) Encoder {
+		return link(marshaller, theType, next)
+	}
+}
+
+// ChainDecodingLinks combines links; each is called with the rest of the chain
+func ChainDecodingLinks(links ...DecodingLink) Decoding {
+	if len(links) == 0 {
+		return func(Unmarshaller, reflect.Type) Decoder { return nil }
+	}
+
+	link, next := links[0], ChainDecodingLinks(links[1:]...)
+	return func(unmarshaller Unmarshaller, theType reflect.Type) Decoder {
+		return link(unmarshaller, theType, next)
+	}
+}
+
+// LinkEncoding makes an encoding a link that is followed by the rest of the
+// chain when it returns nil
+func LinkEncoding(encoding Encoding) EncodingLink {
+	return func(marshaller Marshaller, theType reflect.Type, next Encoding) Encoder {
+		if encoder := encoding(marshaller, theType); encoder != nil {
+			return encoder
+		}
+		return next(marshaller, theType)
+	}
+}
+
+// LinkDecoding makes a decoding a link that is followed by the rest of the
+// chain when it returns nil
+func LinkDecoding(decoding Decoding) DecodingLink {
+	return func(unmarshaller Unmarshaller, theType reflect.Type, next Decoding) Decoder {
+		if decoder := decoding(unmarshaller, theType); decoder != nil {
+			return decoder
+		}
+		return next(unmarshaller, theType)
+	}
+}
@@ -30,23 +30,57 @@ func PeekCheck(scanner Scanner, expected ...ScannerCode) bool {
 	return false
 }
 
-func TryIndirectDecoding(direct Decoder, indirect Decoder) Decoder {
-	return func(scratch [64]byte, scanner Scanner, value reflect.Value) {
-		if value.CanAddr() {
-			indirect(scratch, scanner, value.Addr())
-		} else {
-			direct(scratch, scanner, value)
-		}
+// Rescan returns a scanner for a value that has already been scanned, which
+// reports errors to the original scanner
+func Rescan(scanner Scanner, obj reflect.Value) Scanner {
+	var rescanner Scanner
+	if obj.IsValid() {
+		rescanner = ScanValue(obj.Interface())
+	} else {
+		rescanner = ScanValue(nil)
+	}
+
+	rescanner.SetErrorHandler(scanner.Error)
+	rescanner.Continue()
+
+	// carry the state of the Unmarshal call over
+	if session := scannerSession(scanner); session != nil {
+		rescanner = &sessionScanner{rescanner, session}
 	}
+	return rescanner
+}
+
+// TryIndirectDecoding decodes addressable values through their address with
+// indirect, and other values with direct
+func TryIndirectDecoding(direct Decoder, indirect Decoder) Decoder {
+	return HookDecoder(PointerReceiver, indirect, direct)
 }
 
 func Decodable2Decoding(unmarshaller Unmarshaller, theType reflect.Type) Decoder {
+	return fieldsDecoding(unmarshaller, theType, func(value reflect.Value) []Field {
+		fieldMap := value.Interface().(Decodable2).DecodableFields()
+
+		fields := make([]Field, 0, len(fieldMap))
+		for name, field := range fieldMap {
+			fields = append(fields, Field{Name: name, Value: field})
+		}
+		return fields
+	})
+}
+
+func Decodable3Decoding(unmarshaller Unmarshaller, theType reflect.Type) Decoder {
+	return fieldsDecoding(unmarshaller, theType, func(value reflect.Value) []Field {
+		return value.Interface().(Decodable3).DecodableFieldList()
+	})
+}
+
+func fieldsDecoding(unmarshaller Unmarshaller, theType reflect.Type, list func(reflect.Value) []Field) Decoder {
 	return func(scratch [64]byte, scanner Scanner, value reflect.Value) {
 		if theType.Kind() == reflect.Ptr && value.IsNil() {
 			value.Set(reflect.New(theType.Elem()))
 		}
 
-		fields := value.Interface().(Decodable2).DecodableFields()
+		fields := list(value)
 
 		if scanner.Peek() == ScannedLiteralBegin {
 			null := scanner.NextValue()
@@ -82,24 +116,37 @@ func Decodable2Decoding(unmarshaller Unmarshaller, theType reflect.Type) Decoder
 			keystr := key.String()
 
 			// check by name
-			field := fields[keystr]
+			field := -1
+			for i := range fields {
+				if fields[i].Name == keystr {
+					field = i
+					break
+				}
+			}
 
 			// check by case-folded name (disableable?)
-			if !field.IsValid() {
-				for name, altfield := range fields {
-					if strings.EqualFold(keystr, name) {
-						field = altfield
+			if field < 0 {
+				for i := range fields {
+					if strings.EqualFold(keystr, fields[i].Name) {
+						field = i
 						break
 					}
 				}
 			}
 
 			scanner.Continue()
-			if !field.IsValid() {
+			if field < 0 {
 				scanner.NextValue()
+				continue
+			}
+
+			EnterElement(scanner, fields[field].Name)
+			if fields[field].Decoder != nil {
+				fields[field].Decoder(scratch, scanner, fields[field].Value)
 			} else {
-				unmarshaller.UnmarshalValue(scanner, field)
+				unmarshaller.UnmarshalValue(scanner, fields[field].Value)
 			}
+			ExitElement(scanner)
 		}
 	}
 }
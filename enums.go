@@ -0,0 +1,156 @@
+package gocoding
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Integer is the constraint of the types that can be enums
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// Enum names the values of an integer type. Text encoders write the name of
+// a value instead of its number, and decoders reject names (and numbers) that
+// are not registered. The values of a flag enum are bits, and bitmasks are
+// written as arrays of the names of their bits.
+type Enum struct {
+	Type  reflect.Type
+	Flags bool
+
+	names  map[uint64]string
+	values map[string]uint64
+	bits   []uint64 // sorted
+}
+
+var enums = struct {
+	sync.RWMutex
+	types map[reflect.Type]*Enum
+}{types: make(map[reflect.Type]*Enum)}
+
+// RegisterEnum registers the names of the values of T
+func RegisterEnum[T Integer](names map[T]string) {
+	registerEnum(names, false)
+}
+
+// RegisterFlags registers the names of the bits of T
+func RegisterFlags[T Integer](names map[T]string) {
+	registerEnum(names, true)
+}
+
+func registerEnum[T Integer](names map[T]string, flags bool) {
+	enum := &Enum{
+		Type:   reflect.TypeOf(new(T)).Elem(),
+		Flags:  flags,
+		names:  make(map[uint64]string),
+		values: make(map[string]uint64),
+	}
+
+	for value, name := range names {
+		bits := enumBits(reflect.ValueOf(value))
+		enum.names[bits] = name
+		enum.values[name] = bits
+		enum.bits = append(enum.bits, bits)
+	}
+	sort.Slice(enum.bits, func(i, j int) bool { return enum.bits[i] < enum.bits[j] })
+
+	enums.Lock()
+	enums.types[enum.Type] = enum
+	enums.Unlock()
+}
+
+// LookupEnum returns the enum registered for a type
+func LookupEnum(theType reflect.Type) (*Enum, bool) {
+	enums.RLock()
+	enum, ok := enums.types[theType]
+	enums.RUnlock()
+	return enum, ok
+}
+
+func enumBits(value reflect.Value) uint64 {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return uint64(value.Int())
+
+	default:
+		return value.Uint()
+	}
+}
+
+func (e *Enum) setBits(value reflect.Value, bits uint64) {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value.SetInt(int64(bits))
+
+	default:
+		value.SetUint(bits)
+	}
+}
+
+// Name returns the name of a value
+func (e *Enum) Name(value reflect.Value) (string, bool) {
+	name, ok := e.names[enumBits(value)]
+	return name, ok
+}
+
+// Names returns the names of the bits of a flag value, or false if it has
+// bits that are not registered
+func (e *Enum) Names(value reflect.Value) ([]string, bool) {
+	bits := enumBits(value)
+	names := []string{}
+
+	for _, flag := range e.bits {
+		if flag != 0 && bits&flag == flag {
+			names = append(names, e.names[flag])
+			bits &^= flag
+		}
+	}
+
+	return names, bits == 0
+}
+
+// Set sets a value to the value with the given name
+func (e *Enum) Set(value reflect.Value, name string) bool {
+	bits, ok := e.values[name]
+	if ok {
+		e.setBits(value, bits)
+	}
+	return ok
+}
+
+// SetNumber sets a value to a number, if it is a registered value or, for
+// flags, if all of its bits are registered
+func (e *Enum) SetNumber(value reflect.Value, number int64) bool {
+	bits := uint64(number)
+	_, ok := e.names[bits]
+	if !ok && e.Flags {
+		for _, flag := range e.bits {
+			if bits&flag == flag {
+				bits &^= flag
+			}
+		}
+		ok = bits == 0
+	}
+
+	if ok {
+		e.setBits(value, uint64(number))
+	}
+	return ok
+}
+
+// SetNames sets a flag value to the bits with the given names, or returns the
+// first name that is not registered
+func (e *Enum) SetNames(value reflect.Value, names []string) (string, bool) {
+	var bits uint64
+	for _, name := range names {
+		flag, ok := e.values[name]
+		if !ok {
+			return name, false
+		}
+		bits |= flag
+	}
+
+	e.setBits(value, bits)
+	return "", true
+}
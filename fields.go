@@ -0,0 +1,59 @@
+package gocoding
+
+import (
+	"reflect"
+)
+
+// Field describes a field of an Encodable3 or Decodable3 value
+type Field struct {
+	Name  string
+	Value reflect.Value
+
+	// options, as in a struct tag; omitempty leaves an empty field out when it
+	// is encoded
+	Options []TagOption
+
+	// override the encoder or decoder found for the type of the value
+	Encoder Encoder
+	Decoder Decoder
+}
+
+// NewField describes the value ptr points to, with a `name,option,...` tag
+func NewField(tag string, ptr interface{}) Field {
+	parsed := ParseTagString(tag)
+	return Field{Name: parsed.Name, Value: reflect.ValueOf(ptr).Elem(), Options: parsed.Options}
+}
+
+// Has returns true if the field has the option
+func (f Field) Has(key string) bool {
+	return Tag{Options: f.Options}.Has(key)
+}
+
+// IsEmpty returns true for false, 0, nil, empty collections and strings, and
+// unset Optionals
+func IsEmpty(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+
+	case reflect.Bool:
+		return !value.Bool()
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int() == 0
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return value.Uint() == 0
+
+	case reflect.Float32, reflect.Float64:
+		return value.Float() == 0
+
+	case reflect.Interface, reflect.Ptr:
+		return value.IsNil()
+
+	case reflect.Struct:
+		return IsUnset(value)
+	}
+
+	return false
+}
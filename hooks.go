@@ -0,0 +1,146 @@
+package gocoding
+
+import (
+	"reflect"
+	"strings"
+)
+
+// BeforeMarshaler is implemented by values that check or prepare themselves
+// before they are encoded
+type BeforeMarshaler interface {
+	BeforeMarshal() error
+}
+
+// AfterUnmarshaler is implemented by values that complete themselves after
+// they are decoded
+type AfterUnmarshaler interface {
+	AfterUnmarshal() error
+}
+
+// Validator is implemented by values that check their invariants after they
+// are decoded. Validate is called after AfterUnmarshal, unless AfterUnmarshal
+// failed.
+type Validator interface {
+	Validate() error
+}
+
+var beforeMarshalerType = reflect.TypeOf(new(BeforeMarshaler)).Elem()
+var afterUnmarshalerType = reflect.TypeOf(new(AfterUnmarshaler)).Elem()
+var validatorType = reflect.TypeOf(new(Validator)).Elem()
+
+// HookError is an error returned by a hook, annotated with the path of the
+// value it was called on
+type HookError struct {
+	Path string
+	Err  error
+}
+
+func (e *HookError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *HookError) Unwrap() error {
+	return e.Err
+}
+
+// HookErrors is returned by Marshal and Unmarshal when hooks fail; encoding and
+// decoding continue after a hook fails, so that every failure is reported
+type HookErrors []*HookError
+
+func (e HookErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// hook returns a function that calls a hook method on a value of the type, if
+// the type or a pointer to it implements the interface of the hook. Pointers
+// are skipped, since the hook is called on the value they point to. Hooks with
+// pointer receivers are called on an addressable copy of values that are not
+// addressable, and the function returns the value the hook was called on.
+func hook(theType, hookType reflect.Type, method string) func(reflect.Value) (reflect.Value, error) {
+	if theType.Kind() == reflect.Ptr || theType.Kind() == reflect.Interface {
+		return nil
+	}
+
+	receiver := Implements(theType, hookType)
+	if receiver == NotImplemented {
+		return nil
+	}
+
+	return func(value reflect.Value) (reflect.Value, error) {
+		if receiver == PointerReceiver && !value.CanAddr() {
+			copied := reflect.New(theType).Elem()
+			copied.Set(value)
+			value = copied
+		}
+
+		receiving, ok := Receive(value, receiver)
+		if !ok {
+			return value, nil
+		}
+
+		result := receiving.MethodByName(method).Call(nil)[0]
+		if result.IsNil() {
+			return value, nil
+		}
+		return value, result.Interface().(error)
+	}
+}
+
+// lifecycleEncoder wraps an encoder to call BeforeMarshal before the value is
+// encoded
+func lifecycleEncoder(theType reflect.Type, encoder Encoder) Encoder {
+	before := hook(theType, beforeMarshalerType, "BeforeMarshal")
+	if before == nil || encoder == nil {
+		return encoder
+	}
+
+	return func(scratch [64]byte, renderer Renderer, value reflect.Value) {
+		value, err := before(value)
+		if err != nil {
+			session := rendererSession(renderer)
+			if session == nil {
+				renderer.Error(&Error{"Encoding", err})
+			} else {
+				session.errors = append(session.errors, &HookError{pathString(session.path), err})
+			}
+		}
+
+		encoder(scratch, renderer, value)
+	}
+}
+
+// lifecycleDecoder wraps a decoder to call AfterUnmarshal and Validate after
+// the value is decoded
+func lifecycleDecoder(theType reflect.Type, decoder Decoder) Decoder {
+	after := hook(theType, afterUnmarshalerType, "AfterUnmarshal")
+	validate := hook(theType, validatorType, "Validate")
+	if after == nil && validate == nil || decoder == nil {
+		return decoder
+	}
+
+	return func(scratch [64]byte, scanner Scanner, value reflect.Value) {
+		decoder(scratch, scanner, value)
+
+		var err error
+		if after != nil {
+			value, err = after(value)
+		}
+		if err == nil && validate != nil {
+			_, err = validate(value)
+		}
+		if err == nil {
+			return
+		}
+
+		session := scannerSession(scanner)
+		if session == nil {
+			scanner.Error(&Error{"Decoding", err})
+		} else {
+			session.errors = append(session.errors, &HookError{pathString(session.path), err})
+		}
+	}
+}
@@ -4,11 +4,19 @@ import (
 	"fmt"
 	"github.com/FactomProject/gocoding"
 	"github.com/FactomProject/gocoding/text"
+	"html/template"
 	"io"
+	"reflect"
 )
 
-func NewMarshaller() gocoding.Marshaller {
-	return gocoding.NewMarshaller(text.Encoding)
+func NewMarshaller(options ...gocoding.Option) gocoding.Marshaller {
+	return gocoding.NewMarshaller(Encoding, options...)
+}
+
+// Encoding is the encoding used by html; other encodings can be layered on top
+// of it with gocoding.ChainEncoding
+func Encoding(marshaller gocoding.Marshaller, theType reflect.Type) gocoding.Encoder {
+	return text.Encoding(marshaller, theType)
 }
 
 func Marshal(writer io.Writer, obj interface{}) error {
@@ -49,11 +57,32 @@ func (r *htmlRenderer) WriteNil() int {
 	return n
 }
 
+// PrintString writes a string as HTML text, escaping it so that it is not
+// parsed as markup
 func (r *htmlRenderer) PrintString(str string) int {
-	n, _ := r.Write([]byte(str))
+	n, _ := r.Write([]byte(template.HTMLEscapeString(str)))
 	return n
 }
 
+// RenderString returns a writer that writes a string as HTML text, escaping it
+// as it is written
+func (r *htmlRenderer) RenderString() io.WriteCloser {
+	return htmlStringWriter{r}
+}
+
+type htmlStringWriter struct {
+	renderer *htmlRenderer
+}
+
+func (w htmlStringWriter) Write(data []byte) (int, error) {
+	template.HTMLEscape(w.renderer, data)
+	return len(data), nil
+}
+
+func (w htmlStringWriter) Close() error {
+	return nil
+}
+
 func (r *htmlRenderer) StartStruct() int {
 	return r.Printf(`<ul class="struct">`)
 }
@@ -76,7 +105,7 @@ func (r *htmlRenderer) StopArray() int {
 }
 
 func (r *htmlRenderer) StartElement(id string) int {
-	return r.Printf(`<li class="element"><span>%s: </span>`, id)
+	return r.Printf(`<li class="element"><span>%s: </span>`, template.HTMLEscapeString(id))
 }
 func (r *htmlRenderer) StopElement(id string) int {
 	return r.Printf(`</li>`)
@@ -0,0 +1,43 @@
+package html
+
+import (
+	"bytes"
+	"github.com/FactomProject/gocoding"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestPlaceholders(t *testing.T) {
+	r := struct {
+		A int
+		B func()
+	}{A: 1}
+
+	buf := new(bytes.Buffer)
+	err := NewMarshaller(gocoding.WithUnsupported(gocoding.UnsupportedPlaceholder)).Marshal(Render(buf), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), `<span>B: </span>&lt;func()&gt;</li>`) {
+		t.Errorf("Expected an escaped placeholder, got %s", buf.String())
+	}
+}
+
+func TestReaders(t *testing.T) {
+	type blob struct {
+		Data  io.Reader
+		Bytes []byte
+	}
+
+	buf := new(bytes.Buffer)
+	err := Marshal(buf, blob{strings.NewReader("<x>"), []byte("<x>")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), `<span>Data: </span>3c783e</li>`) || !strings.Contains(buf.String(), `<span>Bytes: </span>3c783e</li>`) {
+		t.Errorf("Expected readers to be written like byte slices, got %s", buf.String())
+	}
+}
@@ -0,0 +1,1132 @@
+package json
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"github.com/FactomProject/gocoding"
+	"github.com/FactomProject/gocoding/text"
+	"io"
+	"iter"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func marshal(obj interface{}, t *testing.T, options ...gocoding.Option) string {
+	buf := new(bytes.Buffer)
+	err := NewMarshaller(options...).Marshal(Render(buf), obj)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	return buf.String()
+}
+
+func unmarshal(json string, obj interface{}, t *testing.T, options ...gocoding.Option) {
+	err := NewUnmarshaller(options...).Unmarshal(Scan(gocoding.ReadString(json)), obj)
+
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestByteArray(t *testing.T) {
+	r := struct{ A [4]byte }{[4]byte{1, 2, 3, 4}}
+
+	s := marshal(r, t)
+	if s != `{"A":"01020304"}` {
+		t.Errorf("Expected hex string, got %s", s)
+	}
+
+	r.A = [4]byte{}
+	unmarshal(s, &r, t)
+	if r.A != [4]byte{1, 2, 3, 4} {
+		t.Errorf("Expected [1 2 3 4], got %v", r.A)
+	}
+
+	s = marshal(r, t, gocoding.WithByteEncoding(gocoding.Base64Bytes))
+	if s != `{"A":"AQIDBA=="}` {
+		t.Errorf("Expected base64 string, got %s", s)
+	}
+
+	scanner := Scan(gocoding.ReadString(`{"A":"010203"}`))
+	scanner.SetErrorHandler(func(err *gocoding.Error) { panic(err) })
+	err := NewUnmarshaller().Unmarshal(scanner, &r)
+	if err == nil || !strings.Contains(err.Error(), "expected 4 bytes, got 3") {
+		t.Errorf("Expected a length error, got %v", err)
+	}
+}
+
+type binaryKey struct {
+	data []byte
+}
+
+func (k *binaryKey) MarshalBinary() ([]byte, error) {
+	return k.data, nil
+}
+
+func (k *binaryKey) UnmarshalBinary(data []byte) error {
+	k.data = data
+	return nil
+}
+
+func TestBinaryMarshaller(t *testing.T) {
+	r := struct {
+		A binaryKey
+		B *binaryKey
+	}{binaryKey{[]byte{0xab}}, nil}
+
+	s := marshal(&struct{ A binaryKey }{r.A}, t)
+	if s != `{"A":"ab"}` {
+		t.Errorf("Expected binary form as hex, got %s", s)
+	}
+
+	s = marshal(&struct{ B *binaryKey }{r.B}, t)
+	if s != `{"B":null}` {
+		t.Errorf("Expected null, got %s", s)
+	}
+
+	unmarshal(`{"A":"cd","B":"ef"}`, &r, t)
+	if !bytes.Equal(r.A.data, []byte{0xcd}) || r.B == nil || !bytes.Equal(r.B.data, []byte{0xef}) {
+		t.Errorf("Binary form was not restored: %v, %v", r.A, r.B)
+	}
+}
+
+func TestTime(t *testing.T) {
+	r := struct {
+		A time.Time
+		B time.Time `gocoding:"b,time=unixmillis"`
+		C time.Duration
+		D *time.Duration `gocoding:"d,duration=string"`
+	}{}
+	r.A = time.Date(2015, 6, 1, 12, 30, 0, 0, time.UTC)
+	r.B = r.A
+	r.C = 90 * time.Minute
+	r.D = &r.C
+
+	s := marshal(r, t, gocoding.WithTimeFormat(gocoding.TimeRFC3339), gocoding.WithDurationFormat(gocoding.DurationSeconds))
+	if s != `{"A":"2015-06-01T12:30:00Z","b":1433161800000,"C":5400,"d":"1h30m0s"}` {
+		t.Errorf("Unexpected time encoding: %s", s)
+	}
+
+	r.A, r.B, r.C, r.D = time.Time{}, time.Time{}, 0, nil
+	unmarshal(s, &r, t, gocoding.WithDurationFormat(gocoding.DurationSeconds))
+	if !r.A.Equal(r.B) || r.B.UnixNano() != 1433161800000*int64(time.Millisecond) || r.C != 90*time.Minute || r.D == nil || *r.D != r.C {
+		t.Errorf("Times were not restored: %v", r)
+	}
+
+	f := struct {
+		A time.Time `gocoding:"a,time=unix"`
+		B time.Time `gocoding:"b,time=unixmillis"`
+		C string
+	}{}
+	unmarshal(`{"a":1433161800.5,"b":1433161800000.5,"C":""}`, &f, t)
+	if f.A.UnixNano() != 1433161800500*int64(time.Millisecond) || f.B.UnixNano() != 1433161800000500000 {
+		t.Errorf("Fractional timestamps were not restored: %v", f)
+	}
+}
+
+type thirdParty struct {
+	hi, lo int
+}
+
+func TestRegister(t *testing.T) {
+	m := NewMarshaller()
+	gocoding.RegisterEncoderFunc(m, func(renderer gocoding.Renderer, v thirdParty) {
+		renderer.PrintString(fmt.Sprintf("%d.%d", v.hi, v.lo))
+	})
+
+	buf := new(bytes.Buffer)
+	err := m.Marshal(Render(buf), struct{ A []thirdParty }{[]thirdParty{{1, 2}}})
+	if err != nil {
+		t.Error(err)
+	}
+	if buf.String() != `{"A":["1.2"]}` {
+		t.Errorf("Registered encoder was not used: %s", buf.String())
+	}
+
+	u := NewUnmarshaller()
+	gocoding.RegisterDecoderFunc(u, func(scanner gocoding.Scanner, v *thirdParty) {
+		fmt.Sscanf(scanner.NextValue().String(), "%d.%d", &v.hi, &v.lo)
+	})
+
+	r := struct{ A *thirdParty }{}
+	err = u.Unmarshal(Scan(gocoding.ReadString(`{"A":"3.4"}`)), &r)
+	if err != nil {
+		t.Error(err)
+	}
+	if r.A == nil || *r.A != (thirdParty{3, 4}) {
+		t.Errorf("Registered decoder was not used: %v", r.A)
+	}
+}
+
+type secret string
+
+func TestChainEncoding(t *testing.T) {
+	redact := func(marshaller gocoding.Marshaller, theType reflect.Type) gocoding.Encoder {
+		if theType != reflect.TypeOf(secret("")) {
+			return nil
+		}
+		return func(scratch [64]byte, renderer gocoding.Renderer, value reflect.Value) {
+			renderer.PrintString("***")
+		}
+	}
+
+	m := gocoding.NewMarshaller(gocoding.ChainEncoding(redact, Encoding))
+
+	buf := new(bytes.Buffer)
+	err := m.Marshal(Render(buf), struct {
+		A string
+		B secret
+	}{"a", "b"})
+	if err != nil {
+		t.Error(err)
+	}
+	if buf.String() != `{"A":"a","B":"***"}` {
+		t.Errorf("Chained encoding was not used: %s", buf.String())
+	}
+}
+
+func TestChainLinks(t *testing.T) {
+	// values that cannot receive MarshalJSON fall back to the next link
+	fallback := func(marshaller gocoding.Marshaller, theType reflect.Type) gocoding.Encoder {
+		if theType != reflect.TypeOf(pointerJSON{}) {
+			return nil
+		}
+		return func(scratch [64]byte, renderer gocoding.Renderer, value reflect.Value) {
+			renderer.PrintString("fallback")
+		}
+	}
+
+	m := gocoding.NewMarshaller(gocoding.ChainEncodingLinks(MarshalerEncoding, gocoding.LinkEncoding(fallback), gocoding.LinkEncoding(text.Encoding)))
+
+	buf := new(bytes.Buffer)
+	r := struct{ P pointerJSON }{pointerJSON{"y"}}
+	if err := m.Marshal(Render(buf), r); err != nil {
+		t.Error(err)
+	}
+	if buf.String() != `{"P":"fallback"}` {
+		t.Errorf("The next link was not used: %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := m.Marshal(Render(buf), &r); err != nil {
+		t.Error(err)
+	}
+	if buf.String() != `{"P":"y"}` {
+		t.Errorf("MarshalJSON was not used: %s", buf.String())
+	}
+}
+
+// rawJSON keeps the text it is decoded from
+type rawJSON struct {
+	text string
+}
+
+func (r *rawJSON) UnmarshalJSON(data []byte) error {
+	r.text = string(data)
+	return nil
+}
+
+// label keeps the text it is decoded from
+type label struct {
+	text string
+}
+
+func (l *label) UnmarshalText(data []byte) error {
+	l.text = string(data)
+	return nil
+}
+
+type annotatedTransaction struct {
+	Note  rawJSON
+	Label label
+}
+
+func (t *annotatedTransaction) amount() int { return 0 }
+
+type transaction interface {
+	amount() int
+}
+
+type factoidTransaction struct{ Amount int }
+
+func (t *factoidTransaction) amount() int { return t.Amount }
+
+type entryCreditTransaction struct{ Credits int }
+
+func (t entryCreditTransaction) amount() int { return t.Credits }
+
+func TestTypeRegistry(t *testing.T) {
+	registry := gocoding.NewTypeRegistry("type")
+	registry.Register("fct", &factoidTransaction{})
+	registry.Register("ec", entryCreditTransaction{})
+
+	r := []transaction{&factoidTransaction{1}, entryCreditTransaction{2}}
+
+	s := marshal(r, t, gocoding.WithTypeRegistry(registry))
+	if s != `[{"type":"fct","Amount":1},{"type":"ec","Credits":2}]` {
+		t.Errorf("Unexpected discriminators: %s", s)
+	}
+
+	r = nil
+	unmarshal(s, &r, t, gocoding.WithTypeRegistry(registry))
+	if len(r) != 2 || r[0].(*factoidTransaction).Amount != 1 || r[1].(entryCreditTransaction).Credits != 2 {
+		t.Errorf("Transactions were not restored: %v", r)
+	}
+
+	// registered types are decoded from a rescan of the scanned object
+	registry.Register("note", &annotatedTransaction{})
+	r = nil
+	unmarshal(`[{"type":"note","Note":{"b":[true,"x"],"a":null},"Label":"l"}]`, &r, t, gocoding.WithTypeRegistry(registry))
+	if len(r) != 1 || r[0].(*annotatedTransaction).Note.text != `{"a":null,"b":[true,"x"]}` || r[0].(*annotatedTransaction).Label.text != "l" {
+		t.Errorf("Raw text was not rescanned as JSON: %v", r)
+	}
+
+	s = marshal([]interface{}{[]int{1, 2}}, t, gocoding.WithTypeRegistry(registry))
+	if s != `[[1,2]]` {
+		t.Errorf("Unexpected unregistered slice encoding: %s", s)
+	}
+
+	registry.Register("list", []int{})
+	renderer := Render(new(bytes.Buffer))
+	renderer.SetErrorHandler(func(err *gocoding.Error) { panic(err) })
+	err := NewMarshaller(gocoding.WithTypeRegistry(registry)).Marshal(renderer, []interface{}{[]int{1, 2}})
+	if err == nil {
+		t.Errorf("Expected an error for a discriminator outside of an object")
+	}
+
+	scanner := Scan(gocoding.ReadString(`[{"type":"fxt","Amount":"3"}]`))
+	scanner.SetErrorHandler(func(err *gocoding.Error) { panic(err) })
+	err = NewUnmarshaller(gocoding.WithTypeRegistry(registry)).Unmarshal(scanner, &r)
+	if err == nil || !strings.Contains(err.Error(), `"fxt" is not a registered type`) {
+		t.Errorf("Expected an unregistered discriminator error, got %v", err)
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	r := struct {
+		A int
+		B func()
+		C chan int
+	}{A: 1}
+
+	s := marshal(r, t, gocoding.WithUnsupported(gocoding.UnsupportedSkip))
+	if s != `{"A":1}` {
+		t.Errorf("Expected unsupported fields to be skipped, got %s", s)
+	}
+
+	s = marshal(r, t, gocoding.WithUnsupported(gocoding.UnsupportedNull))
+	if s != `{"A":1,"B":null,"C":null}` {
+		t.Errorf("Expected unsupported fields to be null, got %s", s)
+	}
+
+	s = marshal(r, t, gocoding.WithUnsupported(gocoding.UnsupportedPlaceholder))
+	if s != `{"A":1,"B":"<func()>","C":"<chan int>"}` {
+		t.Errorf("Expected unsupported fields to be placeholders, got %s", s)
+	}
+
+	unmarshal(`{"A":2,"B":"<func()>","C":null}`, &r, t, gocoding.WithUnsupported(gocoding.UnsupportedPlaceholder))
+	if r.A != 2 || r.B != nil || r.C != nil {
+		t.Errorf("Expected unsupported fields to be ignored, got %v", r)
+	}
+}
+
+type chain struct {
+	Name     string
+	Parent   *chain
+	Children []*chain
+}
+
+func TestReferences(t *testing.T) {
+	root := &chain{Name: "root"}
+	root.Children = []*chain{{Name: "child", Parent: root}}
+
+	renderer := Render(new(bytes.Buffer))
+	renderer.SetErrorHandler(func(err *gocoding.Error) { panic(err) })
+	err := NewMarshaller(gocoding.WithReferences(gocoding.ReferencesError)).Marshal(renderer, root)
+	if err == nil || err.Error() != "Encoding: Pointer cycle at Children.0.Parent" {
+		t.Errorf("Expected a pointer cycle error, got %v", err)
+	}
+
+	s := marshal(root, t, gocoding.WithReferences(gocoding.ReferencesPreserve))
+	if s != `{"$id":"1","Name":"root","Parent":null,"Children":[{"$id":"2","Name":"child","Parent":{"$ref":"1"},"Children":null}]}` {
+		t.Errorf("Unexpected references: %s", s)
+	}
+
+	r := new(chain)
+	unmarshal(s, &r, t, gocoding.WithReferences(gocoding.ReferencesPreserve))
+	if r.Name != "root" || len(r.Children) != 1 || r.Children[0].Parent != r {
+		t.Errorf("References were not restored: %v", r)
+	}
+
+	// each call tracks its own references
+	m := NewMarshaller(gocoding.WithReferences(gocoding.ReferencesPreserve))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := new(bytes.Buffer)
+			if err := m.Marshal(Render(buf), root); err != nil || buf.String() != s {
+				t.Errorf("Unexpected concurrent references: %v, %s", err, buf.String())
+			}
+		}()
+	}
+	wg.Wait()
+
+	// maps do not get ids, since their keys are their own
+	type account struct {
+		Balances *map[string]int
+	}
+	balances := map[string]int{"a": 12}
+	s = marshal(account{&balances}, t, gocoding.WithReferences(gocoding.ReferencesPreserve))
+	if s != `{"Balances":{"a":12}}` {
+		t.Errorf("Unexpected map pointer encoding: %s", s)
+	}
+
+	a := account{}
+	unmarshal(s, &a, t, gocoding.WithReferences(gocoding.ReferencesPreserve))
+	if a.Balances == nil || len(*a.Balances) != 1 || (*a.Balances)["a"] != 12 {
+		t.Errorf("Map pointer was not restored: %v", a.Balances)
+	}
+}
+
+func TestOptional(t *testing.T) {
+	type patch struct {
+		A gocoding.Optional[int]
+		B gocoding.Optional[string]
+		C gocoding.Optional[int]
+	}
+
+	s := marshal(patch{A: gocoding.Some(0), B: gocoding.Null[string]()}, t)
+	if s != `{"A":0,"B":null}` {
+		t.Errorf("Unexpected optional encoding: %s", s)
+	}
+
+	var keys []string
+	presence := gocoding.WithPresence(func(value reflect.Value, present []string) {
+		keys = present
+	})
+
+	r := patch{}
+	unmarshal(`{"A":5,"B":null}`, &r, t, presence)
+	if a, ok := r.A.Get(); !ok || a != 5 || !r.B.IsNull() || r.C.IsSet() {
+		t.Errorf("Unexpected optional decoding: %v", r)
+	}
+	if len(keys) != 2 || keys[0] != "A" || keys[1] != "B" {
+		t.Errorf("Unexpected present keys: %v", keys)
+	}
+}
+
+func TestDecodeMode(t *testing.T) {
+	type limit struct{ Min, Max int }
+	type config struct {
+		Name   string
+		Ports  []int
+		Limits map[string]limit
+	}
+
+	defaults := func() *config {
+		return &config{"default", []int{1, 2, 3}, map[string]limit{"a": {1, 10}, "b": {2, 20}}}
+	}
+	s := `{"Ports":[4],"Limits":{"a":{"Max":99}}}`
+
+	r := defaults()
+	unmarshal(s, r, t)
+	if r.Name != "default" || len(r.Ports) != 1 || r.Limits["a"] != (limit{0, 99}) || r.Limits["b"] != (limit{2, 20}) {
+		t.Errorf("Unexpected merge: %v", r)
+	}
+
+	r = defaults()
+	unmarshal(s, r, t, gocoding.WithDecodeMode(gocoding.DecodeDeepMerge))
+	if r.Name != "default" || len(r.Ports) != 1 || r.Limits["a"] != (limit{1, 99}) || r.Limits["b"] != (limit{2, 20}) {
+		t.Errorf("Unexpected deep merge: %v", r)
+	}
+
+	r = defaults()
+	unmarshal(s, r, t, gocoding.WithDecodeMode(gocoding.DecodeReplace))
+	if r.Name != "" || len(r.Ports) != 1 || r.Limits["a"] != (limit{0, 99}) || len(r.Limits) != 1 {
+		t.Errorf("Unexpected replace: %v", r)
+	}
+}
+
+type service struct {
+	Port    int           `gocoding:"port,default=8080"`
+	Timeout time.Duration `gocoding:"timeout,default=5s"`
+	ChainID string        `gocoding:"chainid,required"`
+	Quota   uint64        `gocoding:"quota,default=18446744073709551615"`
+}
+
+func TestDefaultRequired(t *testing.T) {
+	r := service{}
+
+	unmarshal(`{"chainid":"abc"}`, &r, t)
+	if r.Port != 8080 || r.Timeout != 5*time.Second || r.ChainID != "abc" || r.Quota != ^uint64(0) {
+		t.Errorf("Defaults were not applied: %v", r)
+	}
+
+	// defaults do not replace the values of a pre-populated struct
+	r = service{Port: 9090}
+	unmarshal(`{"chainid":"abc"}`, &r, t)
+	if r.Port != 9090 || r.Timeout != 5*time.Second {
+		t.Errorf("Defaults replaced existing values: %v", r)
+	}
+
+	scanner := Scan(gocoding.ReadString(`{"timeout":"1s"}`))
+	scanner.SetErrorHandler(func(err *gocoding.Error) { panic(err) })
+	err := NewUnmarshaller().Unmarshal(scanner, &r)
+	if err == nil || err.Error() != "Decoding: Missing required key chainid while unmarshalling json.service" {
+		t.Errorf("Expected a missing key error, got %v", err)
+	}
+}
+
+type entryHash struct {
+	Hash string
+}
+
+func (e entryHash) Validate() error {
+	if len(e.Hash) != 4 {
+		return fmt.Errorf("bad hash %q", e.Hash)
+	}
+	return nil
+}
+
+type ledger struct {
+	Name    string
+	Entries []entryHash
+	loaded  bool
+}
+
+func (l *ledger) BeforeMarshal() error {
+	if l.Name == "" {
+		return fmt.Errorf("missing name")
+	}
+	return nil
+}
+
+func (l *ledger) AfterUnmarshal() error {
+	l.loaded = true
+	return nil
+}
+
+func TestHooks(t *testing.T) {
+	r := ledger{}
+	unmarshal(`{"Entries":[{"Hash":"abcd"}],"Name":"a"}`, &r, t)
+	if !r.loaded || len(r.Entries) != 1 {
+		t.Errorf("AfterUnmarshal was not called: %v", r)
+	}
+
+	err := NewUnmarshaller().Unmarshal(Scan(gocoding.ReadString(`{"Entries":[{"Hash":"abcd"},{"Hash":"ab"},{"Hash":""}],"Name":"a"}`)), &r)
+	errs, ok := err.(gocoding.HookErrors)
+	if !ok || len(errs) != 2 || errs[0].Path != "Entries.1" || errs[1].Path != "Entries.2" {
+		t.Errorf("Expected validation errors, got %v", err)
+	}
+
+	err = NewMarshaller().Marshal(Render(new(bytes.Buffer)), &ledger{})
+	if err == nil || err.Error() != "(root): missing name" {
+		t.Errorf("Expected a BeforeMarshal error, got %v", err)
+	}
+
+	err = NewMarshaller().Marshal(Render(new(bytes.Buffer)), ledger{})
+	if err == nil || err.Error() != "(root): missing name" {
+		t.Errorf("Expected a BeforeMarshal error for a value, got %v", err)
+	}
+}
+
+type chainRecord struct {
+	Version string `gocoding:"version"`
+	ChainID string `gocoding:"chain_id,alias=chainid,alias=ChainID"`
+	Owner   string `gocoding:"owner"`
+}
+
+func TestAliases(t *testing.T) {
+	r := chainRecord{}
+	unmarshal(`{"chainid":"abc"}`, &r, t)
+	if r.ChainID != "abc" {
+		t.Errorf("Alias was not matched: %v", r)
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	migrations := gocoding.NewMigrations("version")
+	migrations.Register(chainRecord{}, "", func(obj map[string]interface{}) error {
+		obj["owner"] = obj["user"]
+		delete(obj, "user")
+		obj["version"] = "1"
+		return nil
+	})
+	migrations.Register(chainRecord{}, "1", func(obj map[string]interface{}) error {
+		obj["owner"] = fmt.Sprint("user:", obj["owner"])
+		obj["version"] = "2"
+		return nil
+	})
+
+	r := chainRecord{}
+	unmarshal(`{"chain_id":"abc","user":"bob"}`, &r, t, gocoding.WithMigrations(migrations))
+	if r != (chainRecord{"2", "abc", "user:bob"}) {
+		t.Errorf("Unexpected migration: %v", r)
+	}
+
+	r = chainRecord{}
+	unmarshal(`{"chain_id":"abc","owner":"user:eve","version":"2"}`, &r, t, gocoding.WithMigrations(migrations))
+	if r != (chainRecord{"2", "abc", "user:eve"}) {
+		t.Errorf("Unexpected migration of a current payload: %v", r)
+	}
+}
+
+type peerDocument struct {
+	Name  string
+	Extra map[string]interface{} `gocoding:",inline"`
+}
+
+func TestInline(t *testing.T) {
+	r := peerDocument{}
+	unmarshal(`{"Added":{"A":"x"},"Name":"doc","Tags":["a","b"]}`, &r, t)
+	if r.Name != "doc" || len(r.Extra) != 2 {
+		t.Errorf("Unknown keys were not collected: %v", r)
+	}
+
+	s := marshal(r, t)
+	if s != `{"Name":"doc","Added":{"A":"x"},"Tags":["a","b"]}` {
+		t.Errorf("Unknown keys were not written back: %s", s)
+	}
+
+	// reserved keys are not collected, so they are not written twice
+	registry := gocoding.NewTypeRegistry("type")
+	registry.Register("doc", &peerDocument{})
+	var docs []interface{}
+	unmarshal(`[{"type":"doc","Name":"d","Note":"n"}]`, &docs, t, gocoding.WithTypeRegistry(registry))
+	s = marshal(docs, t, gocoding.WithTypeRegistry(registry))
+	if s != `[{"type":"doc","Name":"d","Note":"n"}]` {
+		t.Errorf("Unexpected discriminator with an inline map: %s", s)
+	}
+
+	p := new(peerDocument)
+	unmarshal(`{"$id":"1","Name":"d"}`, &p, t, gocoding.WithReferences(gocoding.ReferencesPreserve))
+	s = marshal(p, t, gocoding.WithReferences(gocoding.ReferencesPreserve))
+	if len(p.Extra) != 0 || s != `{"$id":"1","Name":"d"}` {
+		t.Errorf("Unexpected id with an inline map: %v, %s", p.Extra, s)
+	}
+}
+
+type envelope struct {
+	Type    string
+	Payload gocoding.RawValue
+}
+
+func TestRawValue(t *testing.T) {
+	r := envelope{}
+	unmarshal(`{"Payload":{"b": [1, 2.5, "x"],"a":null},"Type":"entry"}`, &r, t)
+	if string(r.Payload) != `{"b": [1, 2.5, "x"],"a":null}` {
+		t.Errorf("Unexpected raw value: %s", r.Payload)
+	}
+
+	s := marshal(r, t)
+	if s != `{"Type":"entry","Payload":{"b":[1,2.5,"x"],"a":null}}` {
+		t.Errorf("Unexpected raw value encoding: %s", s)
+	}
+
+	buf := new(bytes.Buffer)
+	renderer := Render(buf)
+	renderer.SetErrorHandler(func(err *gocoding.Error) { panic(err) })
+	err := NewMarshaller().Marshal(renderer, envelope{"entry", gocoding.RawValue(`{"a":}`)})
+	if err == nil {
+		t.Errorf("Expected an invalid raw value error, got %s", buf.String())
+	}
+
+	buf.Reset()
+	err = NewMarshaller().Marshal(renderer, envelope{"entry", gocoding.RawValue(`1],[2`)})
+	if err == nil {
+		t.Errorf("Expected an error for a raw value followed by more text, got %s", buf.String())
+	}
+
+	r = envelope{}
+	unmarshal(`{"Payload":"text","Type":"entry"}`, &r, t)
+	if string(r.Payload) != `"text"` {
+		t.Errorf("Unexpected raw literal: %s", r.Payload)
+	}
+}
+
+type orderedEntry struct {
+	ChainID string
+	Content []byte
+	Extra   []string
+	Height  int
+}
+
+func (e *orderedEntry) EncodableFieldList() []gocoding.Field {
+	return e.DecodableFieldList()
+}
+
+func (e *orderedEntry) DecodableFieldList() []gocoding.Field {
+	return []gocoding.Field{
+		gocoding.NewField("chainid", &e.ChainID),
+		gocoding.NewField("extra,omitempty", &e.Extra),
+		gocoding.NewField("content", &e.Content),
+	}
+}
+
+func TestOrderedFields(t *testing.T) {
+	r := &orderedEntry{ChainID: "abc", Content: []byte{1, 2}}
+
+	s := marshal(r, t)
+	if s != `{"chainid":"abc","content":"0102"}` {
+		t.Errorf("Unexpected ordered encoding: %s", s)
+	}
+
+	r = &orderedEntry{}
+	unmarshal(`{"content":"0304","extra":["x"],"chainid":"def"}`, r, t)
+	if r.ChainID != "def" || !bytes.Equal(r.Content, []byte{3, 4}) || len(r.Extra) != 1 {
+		t.Errorf("Unexpected ordered decoding: %v", r)
+	}
+}
+
+type headers struct {
+	Name   string
+	Custom map[string]string
+	Legacy bool
+}
+
+func (h *headers) DecodeField(key string, unmarshaller gocoding.Unmarshaller, scanner gocoding.Scanner) bool {
+	switch {
+	case len(key) > 2 && key[:2] == "x-":
+		if h.Custom == nil {
+			h.Custom = make(map[string]string)
+		}
+		var value string
+		unmarshaller.UnmarshalObject(scanner, &value)
+		h.Custom[key[2:]] = value
+		return true
+
+	case key == "title":
+		h.Legacy = true
+		unmarshaller.UnmarshalObject(scanner, &h.Name)
+		return true
+	}
+
+	return false
+}
+
+func TestDecodeField(t *testing.T) {
+	r := headers{}
+	unmarshal(`{"x-trace":"abc","title":"old","x-user":"bob"}`, &r, t)
+	if r.Name != "old" || !r.Legacy || r.Custom["trace"] != "abc" || r.Custom["user"] != "bob" {
+		t.Errorf("Unexpected field decoding: %v", r)
+	}
+
+	r = headers{}
+	unmarshal(`{"Name":"new"}`, &r, t)
+	if r.Name != "new" || r.Legacy {
+		t.Errorf("Unexpected field decoding: %v", r)
+	}
+}
+
+type valueFields struct {
+	A string
+}
+
+func (v valueFields) EncodableFields() map[string]reflect.Value {
+	return map[string]reflect.Value{"a": reflect.ValueOf(v.A)}
+}
+
+type pointerJSON struct {
+	A string
+}
+
+func (p *pointerJSON) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + p.A + `"`), nil
+}
+
+func TestReceivers(t *testing.T) {
+	s := marshal(valueFields{"x"}, t)
+	if s != `{"a":"x"}` {
+		t.Errorf("Unexpected value receiver encoding: %s", s)
+	}
+
+	s = marshal(struct{ V *valueFields }{}, t)
+	if s != `{"V":null}` {
+		t.Errorf("Unexpected nil pointer encoding: %s", s)
+	}
+
+	r := struct{ P pointerJSON }{pointerJSON{"y"}}
+	s = marshal(&r, t)
+	if s != `{"P":"y"}` {
+		t.Errorf("Unexpected addressable pointer receiver encoding: %s", s)
+	}
+
+	s = marshal(r, t)
+	if s != `{"P":{"A":"y"}}` {
+		t.Errorf("Unexpected unaddressable pointer receiver encoding: %s", s)
+	}
+}
+
+type Base struct {
+	ID   string
+	Kind string
+}
+
+type audit struct {
+	Created string
+	Kind    string
+}
+
+type Meta struct {
+	Version string
+}
+
+type document struct {
+	*Base
+	audit
+	Meta  `gocoding:"meta"`
+	Title string
+}
+
+func TestEmbedded(t *testing.T) {
+	r := document{&Base{"b1", "k1"}, audit{"today", "k2"}, Meta{"v1"}, "t"}
+	s := marshal(r, t)
+	if s != `{"ID":"b1","Created":"today","meta":{"Version":"v1"},"Title":"t"}` {
+		t.Errorf("Unexpected embedded encoding: %s", s)
+	}
+
+	s = marshal(document{}, t)
+	if s != `{"Created":"","meta":{"Version":""},"Title":""}` {
+		t.Errorf("Unexpected nil embedded pointer encoding: %s", s)
+	}
+
+	r = document{}
+	unmarshal(`{"ID":"b2","Title":"u"}`, &r, t)
+	if r.Base == nil || r.ID != "b2" || r.Title != "u" {
+		t.Errorf("Embedded pointer was not allocated: %v", r)
+	}
+}
+
+type namedKeys struct {
+	ChainID      string
+	HTTPServer   string
+	EntryCount2x string
+	ÉtatÉlan     string
+	Tagged       string `gocoding:"Custom"`
+}
+
+func TestNaming(t *testing.T) {
+	r := namedKeys{"a", "b", "c", "e", "d"}
+
+	expected := map[gocoding.Naming]string{
+		gocoding.NameAsIs:      `{"ChainID":"a","HTTPServer":"b","EntryCount2x":"c","ÉtatÉlan":"e","Custom":"d"}`,
+		gocoding.NameSnakeCase: `{"chain_id":"a","http_server":"b","entry_count2x":"c","état_élan":"e","Custom":"d"}`,
+		gocoding.NameCamelCase: `{"chainId":"a","httpServer":"b","entryCount2x":"c","étatÉlan":"e","Custom":"d"}`,
+		gocoding.NameKebabCase: `{"chain-id":"a","http-server":"b","entry-count2x":"c","état-élan":"e","Custom":"d"}`,
+		gocoding.NameLowerCase: `{"chainid":"a","httpserver":"b","entrycount2x":"c","étatélan":"e","Custom":"d"}`,
+	}
+
+	for naming, json := range expected {
+		s := marshal(r, t, gocoding.WithNaming(naming))
+		if s != json {
+			t.Errorf("Unexpected %s encoding: %s", naming, s)
+		}
+
+		d := namedKeys{}
+		unmarshal(json, &d, t, gocoding.WithNaming(naming))
+		if d != r {
+			t.Errorf("Unexpected %s decoding: %v", naming, d)
+		}
+	}
+}
+
+type internalState struct {
+	Name    string
+	pending []string
+	height  int
+	key     *binaryKey
+}
+
+func TestUnexported(t *testing.T) {
+	r := internalState{"a", []string{"x"}, 3, &binaryKey{[]byte{1}}}
+
+	s := marshal(r, t)
+	if s != `{"Name":"a"}` {
+		t.Errorf("Unexpected encoding: %s", s)
+	}
+
+	// r is not addressable, so it is copied to read its slice and pointer
+	s = marshal(r, t, gocoding.WithUnexported(true))
+	if s != `{"Name":"a","pending":["x"],"height":3,"key":"01"}` {
+		t.Errorf("Unexpected encoding of unexported fields: %s", s)
+	}
+
+	d := internalState{}
+	unmarshal(s, &d, t, gocoding.WithUnexported(true))
+	if d.Name != "a" || d.pending != nil || d.height != 0 || d.key != nil {
+		t.Errorf("Unexported fields were decoded: %v", d)
+	}
+
+	// scalars are read without copying the unaddressable struct
+	s = marshal(meterState{"m", 3, 0.5, true}, t, gocoding.WithUnexported(true))
+	if s != `{"Name":"m","count":3,"ratio":0.5,"open":true}` {
+		t.Errorf("Unexpected encoding of unexported scalars: %s", s)
+	}
+
+	c := countedState{}
+	s = marshal(&c, t, gocoding.WithUnexported(true))
+	if s != `{"count":{"N":1}}` || c.count.N != 0 {
+		t.Errorf("Unexported field was modified by encoding: %s, %v", s, c)
+	}
+}
+
+type markCount struct {
+	N int
+}
+
+func (m *markCount) BeforeMarshal() error {
+	m.N++
+	return nil
+}
+
+type meterState struct {
+	Name  string
+	count int
+	ratio float64
+	open  bool
+}
+
+type countedState struct {
+	count markCount
+}
+
+type peerSets struct {
+	Peers  map[string]struct{} `gocoding:"peers,set"`
+	Online map[string]bool     `gocoding:"online,set"`
+	Flags  map[string]bool     `gocoding:"flags"`
+}
+
+func TestSets(t *testing.T) {
+	r := peerSets{
+		Peers:  map[string]struct{}{"b": {}, "a": {}},
+		Online: map[string]bool{"a": true, "c": false},
+		Flags:  map[string]bool{"x": true},
+	}
+
+	s := marshal(r, t)
+	if s != `{"peers":["a","b"],"online":["a"],"flags":{"x":true}}` {
+		t.Errorf("Unexpected set encoding: %s", s)
+	}
+
+	s = marshal(r, t, gocoding.WithSets(true))
+	if s != `{"peers":["a","b"],"online":["a"],"flags":["x"]}` {
+		t.Errorf("Unexpected set option encoding: %s", s)
+	}
+
+	d := peerSets{}
+	unmarshal(`{"flags":["y"],"online":["b"],"peers":["c","d"]}`, &d, t, gocoding.WithSets(true))
+	if len(d.Peers) != 2 || !d.Online["b"] || !d.Flags["y"] {
+		t.Errorf("Unexpected set decoding: %v", d)
+	}
+}
+
+type status int
+
+const (
+	statusPending status = iota
+	statusActive
+	statusClosed
+)
+
+type permission uint8
+
+const (
+	permRead permission = 1 << iota
+	permWrite
+	permAdmin
+)
+
+type account struct {
+	Status status
+	Perms  permission
+}
+
+func init() {
+	gocoding.RegisterEnum(map[status]string{statusPending: "pending", statusActive: "active", statusClosed: "closed"})
+	gocoding.RegisterFlags(map[permission]string{permRead: "read", permWrite: "write", permAdmin: "admin"})
+}
+
+func TestEnums(t *testing.T) {
+	s := marshal(account{statusActive, permRead | permAdmin}, t)
+	if s != `{"Status":"active","Perms":["read","admin"]}` {
+		t.Errorf("Unexpected enum encoding: %s", s)
+	}
+
+	s = marshal(account{statusClosed, 0}, t)
+	if s != `{"Status":"closed","Perms":[]}` {
+		t.Errorf("Unexpected empty flags encoding: %s", s)
+	}
+
+	r := account{}
+	unmarshal(`{"Status":"closed","Perms":["write","read"]}`, &r, t)
+	if r.Status != statusClosed || r.Perms != permRead|permWrite {
+		t.Errorf("Unexpected enum decoding: %v", r)
+	}
+
+	for _, json := range []string{`{"Status":"open"}`, `{"Perms":["read","exec"]}`, `{"Perms":"read"}`} {
+		scanner := Scan(gocoding.ReadString(json))
+		scanner.SetErrorHandler(func(err *gocoding.Error) { panic(err) })
+		if err := NewUnmarshaller().Unmarshal(scanner, &r); err == nil {
+			t.Errorf("Expected an unknown name error for %s", json)
+		}
+	}
+
+	renderer := Render(new(bytes.Buffer))
+	renderer.SetErrorHandler(func(err *gocoding.Error) { panic(err) })
+	if err := NewMarshaller().Marshal(renderer, account{Status: 9}); err == nil {
+		t.Errorf("Expected an unregistered value error")
+	}
+}
+
+type blob struct {
+	Name string
+	Data io.Reader
+	Out  io.Writer
+}
+
+func TestStreams(t *testing.T) {
+	s := marshal(blob{Name: "a", Data: strings.NewReader("hello")}, t)
+	if s != `{"Name":"a","Data":"68656c6c6f","Out":null}` {
+		t.Errorf("Unexpected reader encoding: %s", s)
+	}
+
+	s = marshal(blob{Name: "a", Data: strings.NewReader("hello")}, t, gocoding.WithByteEncoding(gocoding.Base64Bytes))
+	if s != `{"Name":"a","Data":"aGVsbG8=","Out":null}` {
+		t.Errorf("Unexpected base64 reader encoding: %s", s)
+	}
+
+	// a long string streamed through a reader that only keeps a few runes
+	data := strings.Repeat("streamed é\"", 1000)
+	buf := new(bytes.Buffer)
+	r := blob{Out: buf}
+	json := `{"Name":"b","Out":"` + hex.EncodeToString([]byte(data)) + `"}`
+	err := NewUnmarshaller(gocoding.WithStreamWriters(true)).Unmarshal(Scan(gocoding.Read(strings.NewReader(json), 64)), &r)
+	if err != nil || r.Name != "b" || buf.String() != data {
+		t.Errorf("Unexpected writer decoding: %v, %q", err, buf.String())
+	}
+
+	buf.Reset()
+	unmarshal(`{"Out":"aGVsbG8="}`, &r, t, gocoding.WithStreamWriters(true), gocoding.WithByteEncoding(gocoding.Base64Bytes))
+	if buf.String() != "hello" {
+		t.Errorf("Unexpected base64 writer decoding: %q", buf.String())
+	}
+
+	scanner := Scan(gocoding.ReadString(`{"Out":"686"}`))
+	scanner.SetErrorHandler(func(err *gocoding.Error) { panic(err) })
+	if err := NewUnmarshaller(gocoding.WithStreamWriters(true)).Unmarshal(scanner, &r); err == nil {
+		t.Errorf("Expected an odd length error")
+	}
+
+	// runes split between writes are quoted whole
+	buf.Reset()
+	str := gocoding.RenderString(Render(buf))
+	str.Write([]byte("é")[:1])
+	str.Write([]byte("é\"")[1:])
+	str.Close()
+	if buf.String() != `"é\""` {
+		t.Errorf("Unexpected rendered string: %s", buf.String())
+	}
+
+	// renderers that cannot render strings as they are written get them whole
+	buf.Reset()
+	str = gocoding.RenderString(plainRenderer{Render(buf)})
+	str.Write([]byte("a\""))
+	str.Close()
+	if buf.String() != `"a\""` {
+		t.Errorf("Unexpected held string: %s", buf.String())
+	}
+
+	r = blob{}
+	unmarshal(`{"Name":"c","Out":null}`, &r, t, gocoding.WithStreamWriters(true))
+	if r.Name != "c" || r.Out != nil {
+		t.Errorf("Unexpected null writer decoding: %v", r)
+	}
+}
+
+type plainRenderer struct {
+	gocoding.Renderer
+}
+
+type export struct {
+	Items <-chan string
+	Sizes iter.Seq[int]
+}
+
+type batch struct {
+	Items chan<- string
+}
+
+func TestChannels(t *testing.T) {
+	items := make(chan string)
+	go func() {
+		for _, item := range []string{"a", "b", "c"} {
+			items <- item
+		}
+		close(items)
+	}()
+
+	s := marshal(export{items, slices.Values([]int{1, 2})}, t)
+	if s != `{"Items":["a","b","c"],"Sizes":[1,2]}` {
+		t.Errorf("Unexpected stream encoding: %s", s)
+	}
+
+	s = marshal(export{}, t)
+	if s != `{"Items":null,"Sizes":null}` {
+		t.Errorf("Unexpected nil stream encoding: %s", s)
+	}
+
+	s = marshal(batch{make(chan string)}, t, gocoding.WithUnsupported(gocoding.UnsupportedSkip))
+	if s != `{}` {
+		t.Errorf("Expected a send-only channel to be skipped, got %s", s)
+	}
+
+	e := export{}
+	unmarshal(`{"Items":["a"],"Sizes":[1,2]}`, &e, t, gocoding.WithUnsupported(gocoding.UnsupportedSkip))
+	if e.Items != nil || e.Sizes != nil {
+		t.Errorf("Expected streams that cannot be decoded to be skipped, got %v", e)
+	}
+
+	received := make(chan string)
+	done := make(chan []string)
+	go func() {
+		l := []string{}
+		for item := range received {
+			l = append(l, item)
+		}
+		done <- l
+	}()
+
+	unmarshal(`{"Items":["x","y"]}`, &batch{received}, t)
+	if l := <-done; !reflect.DeepEqual(l, []string{"x", "y"}) {
+		t.Errorf("Unexpected channel decoding: %v", l)
+	}
+
+	unmarshal(`{"Items":null}`, &batch{}, t)
+
+	buffered := make(chan string, 2)
+	scanner := Scan(gocoding.ReadString(`{"Items":["x",{"a":"b"}]}`))
+	scanner.SetErrorHandler(func(err *gocoding.Error) { panic(err) })
+	if err := NewUnmarshaller().Unmarshal(scanner, &batch{buffered}); err == nil {
+		t.Errorf("Expected a channel decoding error")
+	}
+	if item := <-buffered; item != "x" {
+		t.Errorf("Unexpected channel decoding: %q", item)
+	}
+	select {
+	case _, ok := <-buffered:
+		if ok {
+			t.Errorf("Expected the channel to be closed")
+		}
+	default:
+		t.Errorf("Channel was not closed after an error")
+	}
+}
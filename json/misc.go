@@ -8,8 +8,8 @@ import (
 	"reflect"
 )
 
-func NewMarshaller() gocoding.Marshaller {
-	return gocoding.NewMarshaller(Encoding)
+func NewMarshaller(options ...gocoding.Option) gocoding.Marshaller {
+	return gocoding.NewMarshaller(Encoding, options...)
 }
 
 func Marshal(writer io.Writer, obj interface{}) error {
@@ -20,8 +20,8 @@ func MarshalIndent(writer io.Writer, obj interface{}, prefix, indent string) err
 	return NewMarshaller().Marshal(RenderIndented(writer, prefix, indent), obj)
 }
 
-func NewUnmarshaller() gocoding.Unmarshaller {
-	return gocoding.NewUnmarshaller(Decoding)
+func NewUnmarshaller(options ...gocoding.Option) gocoding.Unmarshaller {
+	return gocoding.NewUnmarshaller(Decoding, options...)
 }
 
 func Unmarshal(reader gocoding.SliceableRuneReader, obj interface{}) error {
@@ -30,12 +30,27 @@ func Unmarshal(reader gocoding.SliceableRuneReader, obj interface{}) error {
 
 var jsonMarshallerType = reflect.TypeOf(new(json.Marshaler)).Elem()
 
-func Encoding(marshaller gocoding.Marshaller, theType reflect.Type) gocoding.Encoder {
-	if theType.ConvertibleTo(jsonMarshallerType) {
-		return jsonMarshallerEncoder
+// Encoding is text.Encoding, with support for gocoding.RawValue and
+// json.Marshaler
+var Encoding = gocoding.ChainEncodingLinks(gocoding.LinkEncoding(RawValueEncoding), MarshalerEncoding, gocoding.LinkEncoding(text.Encoding))
+
+// MarshalerEncoding is a link that encodes types that implement
+// json.Marshaler; other types, and values that cannot receive MarshalJSON,
+// are encoded by the rest of the chain
+func MarshalerEncoding(marshaller gocoding.Marshaller, theType reflect.Type, next gocoding.Encoding) gocoding.Encoder {
+	if text.Builtin(theType) {
+		return next(marshaller, theType)
 	}
 
-	return text.Encoding(marshaller, theType)
+	switch receiver := gocoding.Implements(theType, jsonMarshallerType); receiver {
+	case gocoding.ValueReceiver:
+		return gocoding.HookEncoder(receiver, jsonMarshallerEncoder, nil)
+
+	case gocoding.PointerReceiver:
+		return gocoding.HookEncoder(receiver, jsonMarshallerEncoder, next(marshaller, theType))
+	}
+
+	return next(marshaller, theType)
 }
 
 func jsonMarshallerEncoder(scratch [64]byte, renderer gocoding.Renderer, value reflect.Value) {
@@ -49,18 +64,27 @@ func jsonMarshallerEncoder(scratch [64]byte, renderer gocoding.Renderer, value r
 
 var jsonUnmarshallerType = reflect.TypeOf(new(json.Unmarshaler)).Elem()
 
-func Decoding(unmarshaller gocoding.Unmarshaller, theType reflect.Type) gocoding.Decoder {
-	if theType.ConvertibleTo(jsonUnmarshallerType) {
-		return jsonUnmarshallerDecoder
+// Decoding is text.Decoding, with support for gocoding.RawValue and
+// json.Unmarshaler
+var Decoding = gocoding.ChainDecodingLinks(gocoding.LinkDecoding(RawValueDecoding), UnmarshalerDecoding, gocoding.LinkDecoding(text.Decoding))
+
+// UnmarshalerDecoding is a link that decodes types that implement
+// json.Unmarshaler, directly or through a pointer; other types, and values
+// that cannot receive UnmarshalJSON, are decoded by the rest of the chain
+func UnmarshalerDecoding(unmarshaller gocoding.Unmarshaller, theType reflect.Type, next gocoding.Decoding) gocoding.Decoder {
+	if text.Builtin(theType) {
+		return next(unmarshaller, theType)
 	}
 
-	decoder := text.Decoding(unmarshaller, theType)
+	switch receiver := gocoding.Implements(theType, jsonUnmarshallerType); receiver {
+	case gocoding.ValueReceiver:
+		return gocoding.HookDecoder(receiver, jsonUnmarshallerDecoder, nil)
 
-	if reflect.PtrTo(theType).ConvertibleTo(jsonUnmarshallerType) {
-		return gocoding.TryIndirectDecoding(decoder, jsonUnmarshallerDecoder)
+	case gocoding.PointerReceiver:
+		return gocoding.HookDecoder(receiver, jsonUnmarshallerDecoder, next(unmarshaller, theType))
 	}
 
-	return decoder
+	return next(unmarshaller, theType)
 }
 
 func jsonUnmarshallerDecoder(scratch [64]byte, scanner gocoding.Scanner, value reflect.Value) {
@@ -0,0 +1,141 @@
+package json
+
+import (
+	"bytes"
+	"github.com/FactomProject/gocoding"
+	"reflect"
+	"strconv"
+)
+
+var rawValueType = reflect.TypeOf(gocoding.RawValue{})
+
+// RawValueEncoding returns an encoder for gocoding.RawValue, or nil
+func RawValueEncoding(marshaller gocoding.Marshaller, theType reflect.Type) gocoding.Encoder {
+	if theType != rawValueType {
+		return nil
+	}
+
+	return rawValueEncoder
+}
+
+// rawValueEncoder validates the source and copies it to the renderer, so that
+// it is indented like the rest of the document
+func rawValueEncoder(scratch [64]byte, renderer gocoding.Renderer, value reflect.Value) {
+	raw := value.Bytes()
+	if len(raw) == 0 {
+		renderer.WriteNil()
+		return
+	}
+
+	// the scanner only accepts objects and arrays, so wrap the value
+	scanner := Scan(gocoding.ReadSlice([]rune("[" + string(raw) + "]")))
+	scanner.SetErrorHandler(renderer.Error)
+
+	if scanner.Continue() != gocoding.ScannedArrayBegin || scanner.Continue() == gocoding.ScannedArrayEnd {
+		renderer.Error(gocoding.ErrorPrint("JSON Marshal", "Invalid raw value: ", string(raw)))
+		return
+	}
+
+	copyValue(renderer, scanner)
+
+	// the wrapping array must hold the one value, and end the text
+	if scanner.Continue() != gocoding.ScannedArrayEnd || scanner.Continue() != gocoding.ScannedToEnd {
+		renderer.Error(gocoding.ErrorPrint("JSON Marshal", "Invalid raw value: ", string(raw)))
+	}
+}
+
+func copyValue(renderer gocoding.Renderer, scanner gocoding.Scanner) {
+	switch scanner.Peek() {
+	case gocoding.ScannedStructBegin, gocoding.ScannedMapBegin:
+		renderer.StartStruct()
+		for {
+			code := scanner.Continue()
+			if code.Matches(gocoding.ScannedStructEnd, gocoding.ScannedMapEnd) {
+				break
+			}
+
+			if code != gocoding.ScannedKeyBegin {
+				gocoding.PeekCheck(scanner, gocoding.ScannedKeyBegin, gocoding.ScannedStructEnd, gocoding.ScannedMapEnd)
+				return
+			}
+
+			key := scanner.NextValue().String()
+			scanner.Continue()
+
+			renderer.StartElement(key)
+			copyValue(renderer, scanner)
+			renderer.StopElement(key)
+		}
+		renderer.StopStruct()
+
+	case gocoding.ScannedArrayBegin:
+		renderer.StartArray()
+		for i := 0; true; i++ {
+			code := scanner.Continue()
+			if code == gocoding.ScannedArrayEnd {
+				break
+			}
+
+			if code == gocoding.ScannerError {
+				return
+			}
+
+			id := strconv.Itoa(i)
+			renderer.StartElement(id)
+			copyValue(renderer, scanner)
+			renderer.StopElement(id)
+		}
+		renderer.StopArray()
+
+	case gocoding.ScannedLiteralBegin:
+		renderer.Write([]byte(scanner.NextString()))
+
+	default:
+		gocoding.PeekCheck(scanner, gocoding.ScannedStructBegin, gocoding.ScannedArrayBegin, gocoding.ScannedLiteralBegin)
+	}
+}
+
+// RawValueDecoding returns a decoder for gocoding.RawValue, or nil
+func RawValueDecoding(unmarshaller gocoding.Unmarshaller, theType reflect.Type) gocoding.Decoder {
+	if theType != rawValueType {
+		return nil
+	}
+
+	return func(scratch [64]byte, scanner gocoding.Scanner, value reflect.Value) {
+		if isSource(scanner) {
+			value.SetBytes([]byte(scanner.NextString()))
+			return
+		}
+
+		// a value that has already been scanned has no source, so write it
+		obj := scanner.NextValue()
+		if !obj.IsValid() || obj.Kind() == reflect.Interface && obj.IsNil() {
+			value.SetBytes([]byte("null"))
+			return
+		}
+
+		buf := new(bytes.Buffer)
+		err := NewMarshaller().Marshal(Render(buf), obj.Interface())
+		if err != nil {
+			scanner.Error(gocoding.ErrorPrint("JSON Unmarshal", err))
+		}
+		value.SetBytes(buf.Bytes())
+	}
+}
+
+// isSource returns true if the scanner reads JSON text, directly or through
+// other scanners
+func isSource(s gocoding.Scanner) bool {
+	for {
+		switch t := s.(type) {
+		case *scanner:
+			return true
+
+		case interface{ Unwrap() gocoding.Scanner }:
+			s = t.Unwrap()
+
+		default:
+			return false
+		}
+	}
+}
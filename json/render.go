@@ -5,6 +5,7 @@ import (
 	"github.com/FactomProject/gocoding"
 	"io"
 	"strconv"
+	"unicode/utf8"
 )
 
 func Render(writer io.Writer) gocoding.Renderer {
@@ -78,6 +79,54 @@ func (s *jsonRendererStack) PrintString(str string) int {
 	return n
 }
 
+// RenderString writes the opening quote of a string, and returns a writer that
+// quotes what is written to it and writes the closing quote when it is closed
+func (s *jsonRendererStack) RenderString() io.WriteCloser {
+	s.Write([]byte(`"`))
+	return &jsonStringWriter{stack: s}
+}
+
+// jsonStringWriter quotes the runes written to it, keeping a rune that is
+// split between writes until the rest of it is written
+type jsonStringWriter struct {
+	stack   *jsonRendererStack
+	pending []byte
+}
+
+func (w *jsonStringWriter) Write(data []byte) (int, error) {
+	w.pending = append(w.pending, data...)
+
+	end := len(w.pending)
+	for i := end - 1; i >= 0 && i >= end-utf8.UTFMax; i-- {
+		if utf8.RuneStart(w.pending[i]) {
+			if !utf8.FullRune(w.pending[i:]) {
+				end = i
+			}
+			break
+		}
+	}
+
+	w.quote(w.pending[:end])
+	w.pending = append(w.pending[:0], w.pending[end:]...)
+	return len(data), nil
+}
+
+func (w *jsonStringWriter) Close() error {
+	w.quote(w.pending)
+	w.pending = nil
+	w.stack.Write([]byte(`"`))
+	return nil
+}
+
+func (w *jsonStringWriter) quote(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+
+	quoted := strconv.Quote(string(data))
+	w.stack.Write([]byte(quoted[1 : len(quoted)-1]))
+}
+
 func (s *jsonRendererStack) writeIndent() {
 	if !s.indent {
 		return
@@ -1,8 +1,11 @@
 package json
 
 import (
+	"io"
 	"reflect"
 	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
 
 	"github.com/FactomProject/gocoding"
 )
@@ -78,6 +81,111 @@ func (s *scanner) NextString() string {
 	return s.runeReader.Slice().String()
 }
 
+// StreamString writes the contents of the string literal being scanned to a
+// writer as they are read, unescaped, without marking the reader
+func (s *scanner) StreamString(w io.Writer) (bool, error) {
+	if s.Peek() != gocoding.ScannedLiteralBegin || s.mark != markedString {
+		return false, nil
+	}
+
+	var err error
+	buf := make([]byte, 0, 512)
+	flush := func() {
+		if err == nil && len(buf) > 0 {
+			_, err = w.Write(buf)
+		}
+		buf = buf[:0]
+	}
+
+	for {
+		c := s.runeReader.Next()
+
+		switch c {
+		case gocoding.EndOfText:
+			s.Error(gocoding.ErrorPrint("Scanner", "Unexpected end of text in string"))
+			return true, err
+
+		case '"':
+			flush()
+			s.stack, s.step = s.stack[:len(s.stack)-1], stateInObjectOrArrayExpectingComma
+			return true, err
+
+		case '\\':
+			var ok bool
+			if c, ok = s.unescape(); !ok {
+				return true, err
+			}
+		}
+
+		buf = utf8.AppendRune(buf, c)
+		if len(buf) > cap(buf)-utf8.UTFMax {
+			flush()
+		}
+	}
+}
+
+// unescape reads the rest of an escape sequence in a string
+func (s *scanner) unescape() (rune, bool) {
+	switch c := s.runeReader.Next(); c {
+	case '"', '\\', '/':
+		return c, true
+
+	case 'b':
+		return '\b', true
+
+	case 'f':
+		return '\f', true
+
+	case 'n':
+		return '\n', true
+
+	case 'r':
+		return '\r', true
+
+	case 't':
+		return '\t', true
+
+	case 'u':
+		r, ok := s.unescapeUnicode()
+		if ok && utf16.IsSurrogate(r) {
+			// the second half of a surrogate pair must follow
+			if s.runeReader.Next() != '\\' || s.runeReader.Next() != 'u' {
+				s.Error(gocoding.ErrorPrint("Scanner", "Expecting the second half of a surrogate pair"))
+				return 0, false
+			}
+			r2, ok2 := s.unescapeUnicode()
+			r, ok = utf16.DecodeRune(r, r2), ok2
+		}
+		return r, ok
+
+	default:
+		s.Error(gocoding.ErrorPrintf("Scanner", `Expecting ", \, /, b, f, n, r, t, or u, got %c`, c))
+		return 0, false
+	}
+}
+
+func (s *scanner) unescapeUnicode() (rune, bool) {
+	var r rune
+	for i := 0; i < 4; i++ {
+		c := s.runeReader.Next()
+		switch {
+		case '0' <= c && c <= '9':
+			r = r<<4 | (c - '0')
+
+		case 'a' <= c && c <= 'f':
+			r = r<<4 | (c - 'a' + 10)
+
+		case 'A' <= c && c <= 'F':
+			r = r<<4 | (c - 'A' + 10)
+
+		default:
+			s.Error(gocoding.ErrorPrintf("Scanner", "Expecting 0-9, a-f, or A-F, got %c", c))
+			return 0, false
+		}
+	}
+	return r, true
+}
+
 func (s *scanner) nextCode(mark bool) gocoding.ScannerCode {
 	var code gocoding.ScannerCode
 
@@ -5,15 +5,17 @@ import (
 	"sync"
 )
 
-func NewMarshaller(encoding Encoding) Marshaller {
-	return &marshaller{encoding: encoding, cache: make(map[reflect.Type]Encoder)}
+func NewMarshaller(encoding Encoding, options ...Option) Marshaller {
+	return &marshaller{encoding: encoding, options: newOptions(options), cache: make(map[reflect.Type]Encoder), registry: make(map[reflect.Type]Encoder)}
 }
 
 type marshaller struct {
 	encoding Encoding
+	options  *Options
 
 	sync.RWMutex
-	cache map[reflect.Type]Encoder
+	cache    map[reflect.Type]Encoder
+	registry map[reflect.Type]Encoder
 
 	scratch [64]byte
 }
@@ -25,10 +27,23 @@ func (m *marshaller) Marshal(renderer Renderer, obj interface{}) (err error) {
 		}
 	}()
 
-	m.MarshalObject(renderer, obj)
+	session := &marshalSession{}
+	if m.options.References != ReferencesIgnore {
+		session.references = newReferenceTracker(m.options.References)
+	}
+
+	m.MarshalObject(&sessionRenderer{renderer, session}, obj)
+
+	if len(session.errors) > 0 {
+		err = session.errors
+	}
 	return
 }
 
+func (m *marshaller) Options() *Options {
+	return m.options
+}
+
 func (m *marshaller) MarshalObject(renderer Renderer, obj interface{}) {
 	m.MarshalValue(renderer, reflect.ValueOf(obj))
 }
@@ -51,11 +66,19 @@ func (m *marshaller) FindEncoder(theType reflect.Type) (encoder Encoder) {
 	// check the cache
 	m.RLock()
 	encoder = m.cache[theType]
+	registered := m.registry[theType]
 	m.RUnlock()
 	if encoder != nil {
 		return encoder
 	}
 
+	// registered encoders override the encoding
+	if registered != nil {
+		encoder = lifecycleEncoder(theType, registered)
+		m.CacheEncoder(theType, encoder)
+		return encoder
+	}
+
 	switch theType.Kind() {
 	case reflect.Array, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice, reflect.Struct:
 		encoder = m.recurseSafeFindAndCacheEncoder(theType)
@@ -66,10 +89,11 @@ func (m *marshaller) FindEncoder(theType reflect.Type) (encoder Encoder) {
 		/*reflect.Complex64, reflect.Complex128,*/
 		reflect.Float32, reflect.Float64:
 		// simple types don't need locking
-		encoder = m.encoding(m, theType)
+		encoder = lifecycleEncoder(theType, m.encoding(m, theType))
 
 	default:
-		panic(ErrorPrint("Encoding", "Unsupported type: ", theType))
+		// unsupported types are handled according to Options.Unsupported
+		encoder = m.encoding(m, theType)
 	}
 
 	m.CacheEncoder(theType, encoder)
@@ -99,6 +123,8 @@ func (m *marshaller) recurseSafeFindAndCacheEncoder(theType reflect.Type) (encod
 		}
 	}
 
+	encoder = lifecycleEncoder(theType, encoder)
+
 	// unblock the indirect encoder
 	wg.Done()
 	return
@@ -114,3 +140,13 @@ func (m *marshaller) CacheEncoder(theType reflect.Type, encoder Encoder) {
 	m.cache[theType] = encoder
 	m.Unlock()
 }
+
+// RegisterEncoder sets the encoder for a type, overriding the marshaller's
+// encoding. Since encoders for other types may have already found the old
+// encoder, registering clears the cache.
+func (m *marshaller) RegisterEncoder(theType reflect.Type, encoder Encoder) {
+	m.Lock()
+	m.registry[theType] = encoder
+	m.cache = make(map[reflect.Type]Encoder)
+	m.Unlock()
+}
@@ -0,0 +1,99 @@
+package gocoding
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Migration upgrades a scanned object from one version of a type to the next.
+// It modifies the object in place and must change its version.
+type Migration func(obj map[string]interface{}) error
+
+// Migrations upgrades old payloads before they are decoded. When migrations
+// are set with WithMigrations, the unmarshaller reads the version key of each
+// object decoded into a struct type with registered migrations, and applies
+// them in turn until no migration is registered for the object's version.
+type Migrations struct {
+	Key string
+
+	sync.RWMutex
+	steps map[reflect.Type]map[string]Migration
+}
+
+func NewMigrations(key string) *Migrations {
+	return &Migrations{Key: key, steps: make(map[reflect.Type]map[string]Migration)}
+}
+
+func WithMigrations(migrations *Migrations) Option {
+	return func(opts *Options) {
+		opts.Migrations = migrations
+	}
+}
+
+// Register adds a migration from a version of the type of obj, which may be a
+// pointer. Objects that do not have the version key, or where it is null, have
+// the version "".
+func (m *Migrations) Register(obj interface{}, version string, migration Migration) {
+	m.RegisterType(reflect.TypeOf(obj), version, migration)
+}
+
+func (m *Migrations) RegisterType(theType reflect.Type, version string, migration Migration) {
+	for theType.Kind() == reflect.Ptr {
+		theType = theType.Elem()
+	}
+
+	m.Lock()
+	if m.steps[theType] == nil {
+		m.steps[theType] = make(map[string]Migration)
+	}
+	m.steps[theType][version] = migration
+	m.Unlock()
+}
+
+// Has returns true if any migrations are registered for the type
+func (m *Migrations) Has(theType reflect.Type) bool {
+	if m == nil {
+		return false
+	}
+
+	m.RLock()
+	_, ok := m.steps[theType]
+	m.RUnlock()
+	return ok
+}
+
+// Version returns the version of a scanned object
+func (m *Migrations) Version(obj map[string]interface{}) string {
+	version, ok := obj[m.Key]
+	if !ok || version == nil {
+		return ""
+	}
+	return fmt.Sprint(version)
+}
+
+// Migrate upgrades a scanned object to the latest version of the type
+func (m *Migrations) Migrate(theType reflect.Type, obj map[string]interface{}) error {
+	m.RLock()
+	steps := m.steps[theType]
+	m.RUnlock()
+
+	for {
+		version := m.Version(obj)
+
+		m.RLock()
+		migration, ok := steps[version]
+		m.RUnlock()
+		if !ok {
+			return nil
+		}
+
+		if err := migration(obj); err != nil {
+			return fmt.Errorf("migrating %s from version %q: %v", theType, version, err)
+		}
+
+		if m.Version(obj) == version {
+			return fmt.Errorf("migrating %s from version %q did not change the version", theType, version)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package gocoding
 
 import (
 	"fmt"
+	"io"
 	"runtime"
 )
 
@@ -54,3 +55,99 @@ func (s *BasicErrorable) Recover(err interface{}) error {
 func (s *BasicErrorable) SetRecoverHandler(handler func(interface{}) error) {
 	s.recovery = handler
 }
+
+// PrefixElement returns a renderer that writes key and the string value as the
+// first element of the struct or map it renders. Nothing is written if the
+// first thing rendered is not the start of a struct or map; Prefixed reports
+// whether the element was written.
+func PrefixElement(renderer Renderer, key, value string) Renderer {
+	return &prefixRenderer{Renderer: renderer, key: key, value: value}
+}
+
+// Prefixed returns true if the renderer was returned by PrefixElement and has
+// written its element
+func Prefixed(renderer Renderer) bool {
+	prefixed, ok := renderer.(*prefixRenderer)
+	return ok && prefixed.written
+}
+
+type prefixRenderer struct {
+	Renderer
+	key, value string
+
+	// whether anything has been rendered, and whether the prefix was
+	done, written bool
+}
+
+func (r *prefixRenderer) Unwrap() Renderer {
+	return r.Renderer
+}
+
+// begin returns true for the first call that renders anything
+func (r *prefixRenderer) begin() bool {
+	if r.done {
+		return false
+	}
+	r.done = true
+	return true
+}
+
+func (r *prefixRenderer) prefix() int {
+	r.written = true
+
+	n := r.StartElement(r.key)
+	n += r.PrintString(r.value)
+	n += r.StopElement(r.key)
+	return n
+}
+
+func (r *prefixRenderer) StartStruct() int {
+	n := r.Renderer.StartStruct()
+	if r.begin() {
+		n += r.prefix()
+	}
+	return n
+}
+
+func (r *prefixRenderer) StartMap() int {
+	n := r.Renderer.StartMap()
+	if r.begin() {
+		n += r.prefix()
+	}
+	return n
+}
+
+func (r *prefixRenderer) StartArray() int {
+	r.begin()
+	return r.Renderer.StartArray()
+}
+
+func (r *prefixRenderer) Write(data []byte) (int, error) {
+	r.begin()
+	return r.Renderer.Write(data)
+}
+
+func (r *prefixRenderer) Print(args ...interface{}) int {
+	r.begin()
+	return r.Renderer.Print(args...)
+}
+
+func (r *prefixRenderer) Printf(format string, args ...interface{}) int {
+	r.begin()
+	return r.Renderer.Printf(format, args...)
+}
+
+func (r *prefixRenderer) WriteNil() int {
+	r.begin()
+	return r.Renderer.WriteNil()
+}
+
+func (r *prefixRenderer) PrintString(str string) int {
+	r.begin()
+	return r.Renderer.PrintString(str)
+}
+
+func (r *prefixRenderer) RenderString() io.WriteCloser {
+	r.begin()
+	return RenderString(r.Renderer)
+}
@@ -0,0 +1,117 @@
+package gocoding
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Naming is how the keys of struct fields without a tagged name are derived
+// from the names of the fields
+type Naming uint8
+
+const (
+	// the name of the field, as is
+	NameAsIs Naming = iota
+
+	// chain_id
+	NameSnakeCase
+
+	// chainId
+	NameCamelCase
+
+	// chain-id
+	NameKebabCase
+
+	// chainid
+	NameLowerCase
+)
+
+func (n Naming) String() string {
+	switch n {
+	case NameAsIs:
+		return "NameAsIs"
+
+	case NameSnakeCase:
+		return "NameSnakeCase"
+
+	case NameCamelCase:
+		return "NameCamelCase"
+
+	case NameKebabCase:
+		return "NameKebabCase"
+
+	case NameLowerCase:
+		return "NameLowerCase"
+
+	default:
+		return "BadNaming"
+	}
+}
+
+func WithNaming(naming Naming) Option {
+	return func(opts *Options) {
+		opts.Naming = naming
+	}
+}
+
+// Name returns the key for a field name
+func (n Naming) Name(name string) string {
+	switch n {
+	case NameSnakeCase:
+		return strings.ToLower(strings.Join(words(name), "_"))
+
+	case NameKebabCase:
+		return strings.ToLower(strings.Join(words(name), "-"))
+
+	case NameCamelCase:
+		words := words(name)
+		for i, word := range words {
+			word = strings.ToLower(word)
+			if i > 0 {
+				first, size := utf8.DecodeRuneInString(word)
+				word = string(unicode.ToUpper(first)) + word[size:]
+			}
+			words[i] = word
+		}
+		return strings.Join(words, "")
+
+	case NameLowerCase:
+		return strings.ToLower(name)
+
+	default:
+		return name
+	}
+}
+
+// words splits a Go name into words, keeping acronyms together, so that
+// HTTPServerID is split into HTTP, Server and ID
+func words(name string) []string {
+	runes := []rune(name)
+	words := []string{}
+
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		prev, this := runes[i-1], runes[i]
+
+		switch {
+		case this == '_' || this == '-':
+			if start < i {
+				words = append(words, string(runes[start:i]))
+			}
+			start = i + 1
+
+		case unicode.IsUpper(this) && (unicode.IsLower(prev) || unicode.IsDigit(prev)),
+			unicode.IsUpper(this) && unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1]):
+			if start < i {
+				words = append(words, string(runes[start:i]))
+			}
+			start = i
+		}
+	}
+
+	if start < len(runes) {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
@@ -0,0 +1,98 @@
+package gocoding
+
+import (
+	"reflect"
+)
+
+// Optional is a value that may be unset, null, or set. An unset Optional field
+// is left out when its struct is encoded, and is not changed when its struct
+// is decoded from an object that does not include it.
+type Optional[T any] struct {
+	Value   T
+	Present bool
+	Null    bool
+}
+
+// Some returns a set Optional
+func Some[T any](value T) Optional[T] {
+	return Optional[T]{Value: value, Present: true}
+}
+
+// Null returns a null Optional
+func Null[T any]() Optional[T] {
+	return Optional[T]{Present: true, Null: true}
+}
+
+// Get returns the value, and true if the Optional is set and not null
+func (o Optional[T]) Get() (T, bool) {
+	return o.Value, o.Present && !o.Null
+}
+
+// IsSet returns true if the Optional is set or null
+func (o Optional[T]) IsSet() bool {
+	return o.Present
+}
+
+func (o Optional[T]) IsNull() bool {
+	return o.Present && o.Null
+}
+
+func (o Optional[T]) optional() {}
+
+// Optionality is implemented by every Optional
+type Optionality interface {
+	IsSet() bool
+	IsNull() bool
+	optional()
+}
+
+var optionalityType = reflect.TypeOf(new(Optionality)).Elem()
+
+// IsOptional returns true if the type is an Optional
+func IsOptional(theType reflect.Type) bool {
+	return theType.Kind() == reflect.Struct && theType.Implements(optionalityType)
+}
+
+// IsUnset returns true if the value is an Optional that is not set
+func IsUnset(value reflect.Value) bool {
+	return IsOptional(value.Type()) && !value.FieldByName("Present").Bool()
+}
+
+// OptionalEncoding writes an Optional as null or as its value
+func OptionalEncoding(marshaller Marshaller, theType reflect.Type) Encoder {
+	field, _ := theType.FieldByName("Value")
+	encoder := marshaller.FindEncoder(field.Type)
+
+	return func(scratch [64]byte, renderer Renderer, value reflect.Value) {
+		if !value.FieldByName("Present").Bool() || value.FieldByName("Null").Bool() {
+			renderer.WriteNil()
+			return
+		}
+
+		encoder(scratch, renderer, value.FieldByName("Value"))
+	}
+}
+
+// OptionalDecoding reads an Optional from null or from its value
+func OptionalDecoding(unmarshaller Unmarshaller, theType reflect.Type) Decoder {
+	field, _ := theType.FieldByName("Value")
+	decoder := unmarshaller.FindDecoder(field.Type)
+
+	return func(scratch [64]byte, scanner Scanner, value reflect.Value) {
+		value.FieldByName("Present").SetBool(true)
+
+		if scanner.Peek() == ScannedLiteralBegin {
+			null := scanner.NextValue()
+			if null.IsValid() && null.Kind() == reflect.Interface && null.IsNil() {
+				value.FieldByName("Null").SetBool(true)
+				value.FieldByName("Value").Set(reflect.Zero(field.Type))
+				return
+			}
+
+			scanner = Rescan(scanner, null)
+		}
+
+		value.FieldByName("Null").SetBool(false)
+		decoder(scratch, scanner, value.FieldByName("Value"))
+	}
+}
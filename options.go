@@ -0,0 +1,338 @@
+package gocoding
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"reflect"
+)
+
+// Options holds the settings shared by a marshaller or unmarshaller and the
+// encoders and decoders it builds. Since encoders and decoders are cached,
+// options are fixed when the marshaller or unmarshaller is created.
+type Options struct {
+	// how byte slices and byte arrays are written as strings
+	ByteEncoding ByteEncoding
+
+	// how time.Time and time.Duration values are written
+	TimeFormat     TimeFormat
+	DurationFormat DurationFormat
+
+	// concrete types of interface values, see TypeRegistry
+	Types *TypeRegistry
+
+	// what to do with bidirectional channels, functions other than iterators,
+	// unsafe pointers, uintptrs and complex numbers
+	Unsupported UnsupportedPolicy
+
+	// whether pointers are tracked, see ReferencePolicy
+	References ReferencePolicy
+
+	// called after a struct is decoded, with the keys that were present
+	Presence func(value reflect.Value, keys []string)
+
+	// how decoding treats the existing contents of the target, see DecodeMode
+	DecodeMode DecodeMode
+
+	// upgrades of old payloads, see Migrations
+	Migrations *Migrations
+
+	// how the keys of fields without a tagged name are derived from their
+	// names, see Naming
+	Naming Naming
+
+	// for debugging, encode unexported struct fields as well; they are never
+	// decoded. Unexported fields that are not scalars are read with package
+	// unsafe, so they are not available where unsafe is not.
+	Unexported bool
+
+	// write maps of bool or struct{} values as arrays of their keys
+	Sets bool
+
+	// decode byte strings into io.Writer fields by streaming them to the
+	// writer the field holds
+	StreamWriters bool
+}
+
+type Option func(*Options)
+
+func newOptions(options []Option) *Options {
+	opts := new(Options)
+	for _, option := range options {
+		option(opts)
+	}
+	return opts
+}
+
+// Configurable is implemented by marshallers and unmarshallers that have
+// options, such as the ones created by NewMarshaller and NewUnmarshaller
+type Configurable interface {
+	Options() *Options
+}
+
+var defaultOptions = new(Options)
+
+// OptionsOf returns the options of a marshaller or unmarshaller, or the
+// default options if it is not Configurable
+func OptionsOf(coder interface{}) *Options {
+	if c, ok := coder.(Configurable); ok {
+		return c.Options()
+	}
+	return defaultOptions
+}
+
+func WithByteEncoding(encoding ByteEncoding) Option {
+	return func(opts *Options) {
+		opts.ByteEncoding = encoding
+	}
+}
+
+func WithTimeFormat(format TimeFormat) Option {
+	return func(opts *Options) {
+		opts.TimeFormat = format
+	}
+}
+
+func WithDurationFormat(format DurationFormat) Option {
+	return func(opts *Options) {
+		opts.DurationFormat = format
+	}
+}
+
+func WithTypeRegistry(registry *TypeRegistry) Option {
+	return func(opts *Options) {
+		opts.Types = registry
+	}
+}
+
+func WithUnsupported(policy UnsupportedPolicy) Option {
+	return func(opts *Options) {
+		opts.Unsupported = policy
+	}
+}
+
+func WithPresence(presence func(value reflect.Value, keys []string)) Option {
+	return func(opts *Options) {
+		opts.Presence = presence
+	}
+}
+
+func WithUnexported(include bool) Option {
+	return func(opts *Options) {
+		opts.Unexported = include
+	}
+}
+
+func WithSets(sets bool) Option {
+	return func(opts *Options) {
+		opts.Sets = sets
+	}
+}
+
+func WithStreamWriters(stream bool) Option {
+	return func(opts *Options) {
+		opts.StreamWriters = stream
+	}
+}
+
+func WithDecodeMode(mode DecodeMode) Option {
+	return func(opts *Options) {
+		opts.DecodeMode = mode
+	}
+}
+
+type ByteEncoding uint8
+
+const (
+	HexBytes ByteEncoding = iota
+	Base64Bytes
+	Base64URLBytes
+)
+
+func (be ByteEncoding) String() string {
+	switch be {
+	case HexBytes:
+		return "HexBytes"
+
+	case Base64Bytes:
+		return "Base64Bytes"
+
+	case Base64URLBytes:
+		return "Base64URLBytes"
+
+	default:
+		return "BadByteEncoding"
+	}
+}
+
+func (be ByteEncoding) EncodeToString(data []byte) string {
+	switch be {
+	case Base64Bytes:
+		return base64.StdEncoding.EncodeToString(data)
+
+	case Base64URLBytes:
+		return base64.URLEncoding.EncodeToString(data)
+
+	default:
+		return hex.EncodeToString(data)
+	}
+}
+
+func (be ByteEncoding) DecodeString(str string) ([]byte, error) {
+	switch be {
+	case Base64Bytes:
+		return base64.StdEncoding.DecodeString(str)
+
+	case Base64URLBytes:
+		return base64.URLEncoding.DecodeString(str)
+
+	default:
+		return hex.DecodeString(str)
+	}
+}
+
+type UnsupportedPolicy uint8
+
+const (
+	// report an error when a value of an unsupported kind is encountered
+	UnsupportedError UnsupportedPolicy = iota
+
+	// leave struct fields of unsupported kinds out entirely; other values are
+	// written as null
+	UnsupportedSkip
+
+	// write values of unsupported kinds as null
+	UnsupportedNull
+
+	// write values of unsupported kinds as a string naming their type
+	UnsupportedPlaceholder
+)
+
+func (up UnsupportedPolicy) String() string {
+	switch up {
+	case UnsupportedError:
+		return "UnsupportedError"
+
+	case UnsupportedSkip:
+		return "UnsupportedSkip"
+
+	case UnsupportedNull:
+		return "UnsupportedNull"
+
+	case UnsupportedPlaceholder:
+		return "UnsupportedPlaceholder"
+
+	default:
+		return "BadUnsupportedPolicy"
+	}
+}
+
+// IsUnsupported returns true if values of the kind cannot be encoded or decoded
+func IsUnsupported(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer, reflect.Uintptr,
+		reflect.Complex64, reflect.Complex128:
+		return true
+
+	default:
+		return false
+	}
+}
+
+type DecodeMode uint8
+
+const (
+	// structs keep fields that are not decoded, maps keep keys that are not
+	// decoded; decoded map entries, slices and array elements are replaced
+	DecodeMerge DecodeMode = iota
+
+	// the target is zeroed before it is decoded
+	DecodeReplace
+
+	// like DecodeMerge, but decoded map entries, slice and array elements, and
+	// interface values are merged with their existing contents
+	DecodeDeepMerge
+)
+
+func (dm DecodeMode) String() string {
+	switch dm {
+	case DecodeMerge:
+		return "DecodeMerge"
+
+	case DecodeReplace:
+		return "DecodeReplace"
+
+	case DecodeDeepMerge:
+		return "DecodeDeepMerge"
+
+	default:
+		return "BadDecodeMode"
+	}
+}
+
+type TimeFormat uint8
+
+const (
+	TimeRFC3339Nano TimeFormat = iota
+	TimeRFC3339
+	TimeUnixSeconds
+	TimeUnixMillis
+)
+
+var timeFormatNames = map[TimeFormat]string{
+	TimeRFC3339Nano: "rfc3339nano",
+	TimeRFC3339:     "rfc3339",
+	TimeUnixSeconds: "unix",
+	TimeUnixMillis:  "unixmillis",
+}
+
+func (tf TimeFormat) String() string {
+	if name, ok := timeFormatNames[tf]; ok {
+		return name
+	}
+	return "BadTimeFormat"
+}
+
+// ParseTimeFormat parses the name of a time format, as used in the
+// `gocoding:",time=name"` tag option
+func ParseTimeFormat(name string) (TimeFormat, bool) {
+	for format, fname := range timeFormatNames {
+		if fname == name {
+			return format, true
+		}
+	}
+	return 0, false
+}
+
+type DurationFormat uint8
+
+const (
+	DurationNanoseconds DurationFormat = iota
+	DurationMilliseconds
+	DurationSeconds
+	DurationString
+)
+
+var durationFormatNames = map[DurationFormat]string{
+	DurationNanoseconds:  "ns",
+	DurationMilliseconds: "ms",
+	DurationSeconds:      "s",
+	DurationString:       "string",
+}
+
+func (df DurationFormat) String() string {
+	if name, ok := durationFormatNames[df]; ok {
+		return name
+	}
+	return "BadDurationFormat"
+}
+
+// ParseDurationFormat parses the name of a duration format, as used in the
+// `gocoding:",duration=name"` tag option
+func ParseDurationFormat(name string) (DurationFormat, bool) {
+	for format, fname := range durationFormatNames {
+		if fname == name {
+			return format, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,7 @@
+package gocoding
+
+// RawValue is the source text of a value. A format that supports it decodes a
+// RawValue by storing the source of the value without interpreting it, and
+// encodes a RawValue by writing that source back, so that part of a document
+// can be decoded later.
+type RawValue []byte
@@ -0,0 +1,120 @@
+package gocoding
+
+import (
+	"reflect"
+)
+
+// Receiver is how a type implements a hook interface, such as Encodable1 or
+// encoding.TextMarshaler
+type Receiver uint8
+
+const (
+	// neither the type nor a pointer to it implements the interface
+	NotImplemented Receiver = iota
+
+	// the type implements the interface
+	ValueReceiver
+
+	// only a pointer to the type implements the interface, so the methods can
+	// only be called on addressable values
+	PointerReceiver
+)
+
+func (r Receiver) String() string {
+	switch r {
+	case NotImplemented:
+		return "NotImplemented"
+
+	case ValueReceiver:
+		return "ValueReceiver"
+
+	case PointerReceiver:
+		return "PointerReceiver"
+
+	default:
+		return "BadReceiver"
+	}
+}
+
+// Implements returns how a type implements an interface
+func Implements(theType, iface reflect.Type) Receiver {
+	if theType.Implements(iface) {
+		return ValueReceiver
+	}
+
+	if theType.Kind() != reflect.Ptr && theType.Kind() != reflect.Interface && reflect.PtrTo(theType).Implements(iface) {
+		return PointerReceiver
+	}
+
+	return NotImplemented
+}
+
+// Receive returns the value to call the methods of an interface on, or false if
+// there is none: the value is a nil pointer or interface, or it is not
+// addressable and the methods have pointer receivers
+func Receive(value reflect.Value, receiver Receiver) (reflect.Value, bool) {
+	switch receiver {
+	case ValueReceiver:
+		if (value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface) && value.IsNil() {
+			return value, false
+		}
+		return value, true
+
+	case PointerReceiver:
+		if !value.CanAddr() {
+			return value, false
+		}
+		return value.Addr(), true
+
+	default:
+		return value, false
+	}
+}
+
+// HookEncoder returns an encoder for a type that implements an interface.
+// Encoder is called with the value to call the methods of the interface on.
+// Nil pointers and interfaces are written as null, and values that cannot
+// receive the methods are encoded with fallback, if it is not nil.
+func HookEncoder(receiver Receiver, encoder, fallback Encoder) Encoder {
+	return func(scratch [64]byte, renderer Renderer, value reflect.Value) {
+		if receiving, ok := Receive(value, receiver); ok {
+			encoder(scratch, renderer, receiving)
+			return
+		}
+
+		switch {
+		case (value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface) && value.IsNil():
+			renderer.WriteNil()
+
+		case fallback != nil:
+			fallback(scratch, renderer, value)
+
+		default:
+			renderer.Error(ErrorPrintf("Encoding", "Cannot encode %s: the value is not addressable", value.Type()))
+		}
+	}
+}
+
+// HookDecoder returns a decoder for a type that implements an interface.
+// Decoder is called with the value to call the methods of the interface on.
+// Nil pointers are allocated first, and values that cannot receive the
+// methods are decoded with fallback, if it is not nil.
+func HookDecoder(receiver Receiver, decoder, fallback Decoder) Decoder {
+	return func(scratch [64]byte, scanner Scanner, value reflect.Value) {
+		if receiver == ValueReceiver && value.Kind() == reflect.Ptr && value.IsNil() && value.CanSet() {
+			value.Set(reflect.New(value.Type().Elem()))
+		}
+
+		if receiving, ok := Receive(value, receiver); ok {
+			decoder(scratch, scanner, receiving)
+			return
+		}
+
+		if fallback != nil {
+			fallback(scratch, scanner, value)
+			return
+		}
+
+		scanner.Error(ErrorPrintf("Decoding", "Cannot decode %s: the value is nil or not addressable", value.Type()))
+	}
+}
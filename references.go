@@ -0,0 +1,173 @@
+package gocoding
+
+import (
+	"reflect"
+	"strconv"
+)
+
+const (
+	// the key of the id of an object, when references are preserved
+	IDKey = "$id"
+
+	// the key of a reference to an object, when references are preserved
+	RefKey = "$ref"
+)
+
+type ReferencePolicy uint8
+
+const (
+	// follow pointers without tracking them; a pointer cycle will recurse
+	// until the stack overflows
+	ReferencesIgnore ReferencePolicy = iota
+
+	// report an error naming the path of a pointer cycle
+	ReferencesError
+
+	// write each struct that is pointed to once, with an $id, and write
+	// {"$ref": id} for every other pointer to it; pointers to other types are
+	// tracked as with ReferencesError
+	ReferencesPreserve
+)
+
+func (rp ReferencePolicy) String() string {
+	switch rp {
+	case ReferencesIgnore:
+		return "ReferencesIgnore"
+
+	case ReferencesError:
+		return "ReferencesError"
+
+	case ReferencesPreserve:
+		return "ReferencesPreserve"
+
+	default:
+		return "BadReferencePolicy"
+	}
+}
+
+func WithReferences(policy ReferencePolicy) Option {
+	return func(opts *Options) {
+		opts.References = policy
+	}
+}
+
+type pointerKey struct {
+	reflect.Type
+	pointer uintptr
+}
+
+// referenceTracker tracks the pointers visited during a Marshal call
+type referenceTracker struct {
+	policy ReferencePolicy
+	active map[pointerKey]bool
+	ids    map[pointerKey]string
+	nextID int
+}
+
+func newReferenceTracker(policy ReferencePolicy) *referenceTracker {
+	return &referenceTracker{policy: policy, active: make(map[pointerKey]bool), ids: make(map[pointerKey]string)}
+}
+
+// enter returns the renderer to encode the pointer's element with, or false if
+// the element should not be encoded
+func (t *referenceTracker) enter(renderer Renderer, value reflect.Value, path []string) (Renderer, bool) {
+	key := pointerKey{value.Type(), value.Pointer()}
+
+	// only structs get ids, since the keys of a map are its own
+	if t.policy == ReferencesPreserve && value.Type().Elem().Kind() == reflect.Struct {
+		if id, ok := t.ids[key]; ok {
+			renderer.StartStruct()
+			renderer.StartElement(RefKey)
+			renderer.PrintString(id)
+			renderer.StopElement(RefKey)
+			renderer.StopStruct()
+			return renderer, false
+		}
+
+		t.nextID++
+		id := strconv.Itoa(t.nextID)
+		t.ids[key] = id
+		return PrefixElement(renderer, IDKey, id), true
+	}
+
+	if t.active[key] {
+		renderer.Error(ErrorPrintf("Encoding", "Pointer cycle at %s", pathString(path)))
+		return renderer, false
+	}
+
+	t.active[key] = true
+	return renderer, true
+}
+
+func (t *referenceTracker) exit(renderer Renderer, value reflect.Value) {
+	key := pointerKey{value.Type(), value.Pointer()}
+	delete(t.active, key)
+
+	// the element was not rendered as an object, so the id was not written
+	if prefixed, ok := renderer.(*prefixRenderer); ok && prefixed.key == IDKey && !prefixed.written {
+		delete(t.ids, key)
+	}
+}
+
+// ReferenceKey returns the value of the $id or $ref key of a scanned object
+func ReferenceKey(obj reflect.Value, key string) (string, bool) {
+	if obj.Kind() != reflect.Map {
+		return "", false
+	}
+
+	id := obj.MapIndex(reflect.ValueOf(key))
+	if id.IsValid() && id.Kind() == reflect.Interface {
+		id = id.Elem()
+	}
+
+	if !id.IsValid() || id.Kind() != reflect.String {
+		return "", false
+	}
+
+	return id.String(), true
+}
+
+// EnterPointer tracks a pointer according to Options.References, for the
+// Marshal call the renderer belongs to. It returns the renderer to encode the
+// pointer's element with, or false if the element should not be encoded;
+// ExitPointer must be called with that renderer.
+func EnterPointer(renderer Renderer, value reflect.Value) (Renderer, bool) {
+	session := rendererSession(renderer)
+	if session == nil || session.references == nil {
+		return renderer, true
+	}
+
+	return session.references.enter(renderer, value, session.path)
+}
+
+func ExitPointer(renderer Renderer, value reflect.Value) {
+	session := rendererSession(renderer)
+	if session == nil || session.references == nil {
+		return
+	}
+
+	session.references.exit(renderer, value)
+}
+
+// Reference returns the pointer decoded with an id, when references are
+// preserved, in the Unmarshal call the scanner belongs to
+func Reference(scanner Scanner, id string) (reflect.Value, bool) {
+	session := scannerSession(scanner)
+	if session == nil {
+		return reflect.Value{}, false
+	}
+
+	pointer, ok := session.references[id]
+	return pointer, ok
+}
+
+// SetReference records the pointer decoded with an id, when references are
+// preserved
+func SetReference(scanner Scanner, id string, pointer reflect.Value) {
+	session := scannerSession(scanner)
+	if session == nil || session.references == nil {
+		return
+	}
+
+	session.references[id] = pointer
+}
@@ -0,0 +1,35 @@
+package gocoding
+
+import (
+	"reflect"
+)
+
+// RegisterEncoderFunc registers an encoder for T, built from a function that
+// renders a T
+func RegisterEncoderFunc[T any](marshaller Marshaller, encode func(Renderer, T)) {
+	theType := reflect.TypeOf(new(T)).Elem()
+
+	marshaller.RegisterEncoder(theType, func(scratch [64]byte, renderer Renderer, value reflect.Value) {
+		var obj T
+		reflect.ValueOf(&obj).Elem().Set(value)
+		encode(renderer, obj)
+	})
+}
+
+// RegisterDecoderFunc registers a decoder for T, built from a function that
+// scans into a *T
+func RegisterDecoderFunc[T any](unmarshaller Unmarshaller, decode func(Scanner, *T)) {
+	theType := reflect.TypeOf(new(T)).Elem()
+
+	unmarshaller.RegisterDecoder(theType, func(scratch [64]byte, scanner Scanner, value reflect.Value) {
+		if value.CanAddr() {
+			decode(scanner, value.Addr().Interface().(*T))
+			return
+		}
+
+		ptr := reflect.New(theType)
+		ptr.Elem().Set(value)
+		decode(scanner, ptr.Interface().(*T))
+		value.Set(ptr.Elem())
+	})
+}
@@ -0,0 +1,115 @@
+package gocoding
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// marshalSession holds the state of a single Marshal call
+type marshalSession struct {
+	path       []string
+	references *referenceTracker
+	errors     HookErrors
+}
+
+// unmarshalSession holds the state of a single Unmarshal call
+type unmarshalSession struct {
+	path       []string
+	references map[string]reflect.Value
+	errors     HookErrors
+}
+
+func pathString(path []string) string {
+	if len(path) == 0 {
+		return "(root)"
+	}
+	return strings.Join(path, ".")
+}
+
+// sessionRenderer carries the state of a Marshal call, and records the path of
+// the element being rendered
+type sessionRenderer struct {
+	Renderer
+	session *marshalSession
+}
+
+func (r *sessionRenderer) Unwrap() Renderer {
+	return r.Renderer
+}
+
+func (r *sessionRenderer) StartElement(id string) int {
+	r.session.path = append(r.session.path, id)
+	return r.Renderer.StartElement(id)
+}
+
+func (r *sessionRenderer) StopElement(id string) int {
+	r.session.path = r.session.path[:len(r.session.path)-1]
+	return r.Renderer.StopElement(id)
+}
+
+// sessionScanner carries the state of an Unmarshal call
+type sessionScanner struct {
+	Scanner
+	session *unmarshalSession
+}
+
+func (s *sessionScanner) Unwrap() Scanner {
+	return s.Scanner
+}
+
+// rendererSession finds the state of the Marshal call a renderer belongs to.
+// Renderers that wrap another renderer must implement Unwrap() Renderer for
+// the state to be found.
+func rendererSession(renderer Renderer) *marshalSession {
+	for {
+		switch r := renderer.(type) {
+		case *sessionRenderer:
+			return r.session
+
+		case interface{ Unwrap() Renderer }:
+			renderer = r.Unwrap()
+
+		default:
+			return nil
+		}
+	}
+}
+
+// scannerSession finds the state of the Unmarshal call a scanner belongs to
+func scannerSession(scanner Scanner) *unmarshalSession {
+	for {
+		switch s := scanner.(type) {
+		case *sessionScanner:
+			return s.session
+
+		case interface{ Unwrap() Scanner }:
+			scanner = s.Unwrap()
+
+		default:
+			return nil
+		}
+	}
+}
+
+// EnterElement records that the scanner is decoding the element with the
+// given key, so that errors can be annotated with its path; it must be paired
+// with ExitElement
+func EnterElement(scanner Scanner, id string) {
+	if session := scannerSession(scanner); session != nil {
+		session.path = append(session.path, id)
+	}
+}
+
+// EnterIndex is EnterElement for the elements of arrays and slices
+func EnterIndex(scanner Scanner, index int) {
+	if session := scannerSession(scanner); session != nil {
+		session.path = append(session.path, strconv.Itoa(index))
+	}
+}
+
+func ExitElement(scanner Scanner) {
+	if session := scannerSession(scanner); session != nil {
+		session.path = session.path[:len(session.path)-1]
+	}
+}
@@ -0,0 +1,143 @@
+package gocoding
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+)
+
+// StringStreamer is implemented by scanners that can write the contents of a
+// string literal to a writer as it is scanned, so that long strings are never
+// held in memory. StreamString returns false, without scanning anything, if
+// the literal being scanned is not a string.
+type StringStreamer interface {
+	StreamString(w io.Writer) (bool, error)
+}
+
+// StreamString streams the string literal being scanned to a writer, if the
+// scanner, or a scanner it wraps, is a StringStreamer
+func StreamString(scanner Scanner, w io.Writer) (bool, error) {
+	for {
+		switch s := scanner.(type) {
+		case StringStreamer:
+			return s.StreamString(w)
+
+		case interface{ Unwrap() Scanner }:
+			scanner = s.Unwrap()
+
+		default:
+			return false, nil
+		}
+	}
+}
+
+// StringRenderer is implemented by renderers that can write a string as it is
+// produced, so that long strings are never held in memory. The contents of the
+// string are written to the writer RenderString returns, and the string ends
+// when the writer is closed.
+type StringRenderer interface {
+	RenderString() io.WriteCloser
+}
+
+// RenderString returns a writer for a string, which renders the string as it
+// is written if the renderer, or a renderer it wraps, is a StringRenderer;
+// otherwise the string is written with PrintString when the writer is closed
+func RenderString(renderer Renderer) io.WriteCloser {
+	for r := renderer; ; {
+		switch s := r.(type) {
+		case StringRenderer:
+			return s.RenderString()
+
+		case interface{ Unwrap() Renderer }:
+			r = s.Unwrap()
+
+		default:
+			return &heldString{renderer: renderer}
+		}
+	}
+}
+
+// heldString holds a string until it is closed, for renderers that cannot
+// render strings as they are written
+type heldString struct {
+	bytes.Buffer
+	renderer Renderer
+}
+
+func (h *heldString) Close() error {
+	h.renderer.PrintString(h.String())
+	return nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// NewEncoder returns a writer that encodes the data written to it and writes
+// the text to w; Close must be called to flush any partial block
+func (be ByteEncoding) NewEncoder(w io.Writer) io.WriteCloser {
+	switch be {
+	case Base64Bytes:
+		return base64.NewEncoder(base64.StdEncoding, w)
+
+	case Base64URLBytes:
+		return base64.NewEncoder(base64.URLEncoding, w)
+
+	default:
+		return nopWriteCloser{hex.NewEncoder(w)}
+	}
+}
+
+// NewDecoder returns a writer that decodes the text written to it and writes
+// the data to w; Close reports text that ends in the middle of a block
+func (be ByteEncoding) NewDecoder(w io.Writer) io.WriteCloser {
+	block := 2
+	if be == Base64Bytes || be == Base64URLBytes {
+		block = 4
+	}
+
+	return &byteDecoder{encoding: be, block: block, w: w}
+}
+
+// byteDecoder decodes whole blocks of text as they are written, and keeps the
+// rest until more text is written
+type byteDecoder struct {
+	encoding ByteEncoding
+	block    int
+	w        io.Writer
+	pending  []byte
+}
+
+func (d *byteDecoder) Write(text []byte) (int, error) {
+	d.pending = append(d.pending, text...)
+
+	n := len(d.pending) - len(d.pending)%d.block
+	if n == 0 {
+		return len(text), nil
+	}
+
+	data, err := d.encoding.DecodeString(string(d.pending[:n]))
+	if err != nil {
+		return 0, err
+	}
+	d.pending = append(d.pending[:0], d.pending[n:]...)
+
+	if _, err := d.w.Write(data); err != nil {
+		return 0, err
+	}
+	return len(text), nil
+}
+
+func (d *byteDecoder) Close() error {
+	if len(d.pending) == 0 {
+		return nil
+	}
+
+	_, err := d.encoding.DecodeString(string(d.pending))
+	return err
+}
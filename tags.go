@@ -0,0 +1,58 @@
+package gocoding
+
+import (
+	"reflect"
+	"strings"
+)
+
+const TagName = "gocoding"
+
+// Tag is a parsed `gocoding:"name,option,key=value"` struct tag
+type Tag struct {
+	Name    string
+	Options []TagOption
+}
+
+type TagOption struct {
+	Key   string
+	Value string
+}
+
+func ParseTag(sf reflect.StructField) Tag {
+	return ParseTagString(sf.Tag.Get(TagName))
+}
+
+func ParseTagString(str string) Tag {
+	parts := strings.Split(str, ",")
+
+	tag := Tag{Name: parts[0]}
+	for _, part := range parts[1:] {
+		if part == "" {
+			continue
+		}
+
+		option := TagOption{Key: part}
+		if i := strings.Index(part, "="); i >= 0 {
+			option.Key, option.Value = part[:i], part[i+1:]
+		}
+		tag.Options = append(tag.Options, option)
+	}
+
+	return tag
+}
+
+// Has returns true if the tag includes the option
+func (t Tag) Has(key string) bool {
+	_, ok := t.Get(key)
+	return ok
+}
+
+// Get returns the value of the first instance of the option
+func (t Tag) Get(key string) (string, bool) {
+	for _, option := range t.Options {
+		if option.Key == key {
+			return option.Value, true
+		}
+	}
+	return "", false
+}
@@ -3,7 +3,6 @@ package text
 import (
 	"encoding"
 	"encoding/hex"
-	"fmt"
 	"github.com/FactomProject/gocoding"
 	"reflect"
 	"strings"
@@ -20,10 +19,28 @@ func GVTS(value reflect.Value) string {
 }
 
 var textUnmarshallerType = reflect.TypeOf(new(encoding.TextUnmarshaler)).Elem()
+var binaryUnmarshallerType = reflect.TypeOf(new(encoding.BinaryUnmarshaler)).Elem()
+var fieldDecodableType = reflect.TypeOf(new(gocoding.FieldDecodable)).Elem()
 
 func Decoding(unmarshaller gocoding.Unmarshaller, theType reflect.Type) gocoding.Decoder {
-	if theType.ConvertibleTo(textUnmarshallerType) {
-		return textUnmarshallerDecoder
+	if Builtin(theType) {
+		return builtinDecoding(unmarshaller, theType)
+	}
+
+	if gocoding.IsOptional(theType) {
+		return gocoding.OptionalDecoding(unmarshaller, theType)
+	}
+
+	if enum, ok := gocoding.LookupEnum(theType); ok {
+		return EnumDecoding(unmarshaller, enum)
+	}
+
+	if gocoding.Implements(theType, textUnmarshallerType) == gocoding.ValueReceiver {
+		return gocoding.HookDecoder(gocoding.ValueReceiver, textUnmarshallerDecoder, nil)
+	}
+
+	if gocoding.Implements(theType, binaryUnmarshallerType) == gocoding.ValueReceiver {
+		return BinaryUnmarshallerDecoding(unmarshaller, theType)
 	}
 
 	var decoder gocoding.Decoder
@@ -34,7 +51,11 @@ func Decoding(unmarshaller gocoding.Unmarshaller, theType reflect.Type) gocoding
 		decoder = decoderType{theType}.decode
 
 	case reflect.Interface:
-		decoder = InterfaceDecoding(unmarshaller, theType)
+		if gocoding.OptionsOf(unmarshaller).StreamWriters && theType.Implements(writerType) {
+			decoder = WriterDecoding(unmarshaller, theType)
+		} else {
+			decoder = InterfaceDecoding(unmarshaller, theType)
+		}
 
 	case reflect.Struct:
 		decoder = StructDecoding(unmarshaller, theType)
@@ -46,22 +67,47 @@ func Decoding(unmarshaller gocoding.Unmarshaller, theType reflect.Type) gocoding
 		decoder = SliceDecoding(unmarshaller, theType)
 
 	case reflect.Array:
-		decoder = ArrayDecoding(unmarshaller, theType)
+		if theType.Elem().Kind() == reflect.Uint8 {
+			decoder = ByteArrayDecoding(unmarshaller, theType)
+		} else {
+			decoder = ArrayDecoding(unmarshaller, theType)
+		}
 
 	case reflect.Ptr:
 		decoder = PtrDecoding(unmarshaller, theType)
 
+	case reflect.Chan:
+		decoder = ChanDecoding(unmarshaller, theType)
+
 	default:
-		decoder = gocoding.ErrorDecoding(gocoding.ErrorPrint("Decoding", "Unsupported type: ", GTTS(theType)))
+		decoder = UnsupportedDecoding(unmarshaller, theType)
+	}
+
+	// decode addressable values with the pointer's hooks
+	if gocoding.Implements(theType, textUnmarshallerType) == gocoding.PointerReceiver {
+		return gocoding.HookDecoder(gocoding.PointerReceiver, textUnmarshallerDecoder, decoder)
 	}
 
-	if reflect.PtrTo(theType).ConvertibleTo(textUnmarshallerType) {
-		return gocoding.TryIndirectDecoding(decoder, textUnmarshallerDecoder)
+	if gocoding.Implements(theType, binaryUnmarshallerType) == gocoding.PointerReceiver {
+		return gocoding.HookDecoder(gocoding.PointerReceiver, BinaryUnmarshallerDecoding(unmarshaller, reflect.PtrTo(theType)), decoder)
 	}
 
 	return decoder
 }
 
+// UnsupportedDecoding handles types that cannot be decoded, according to the
+// unmarshaller's unsupported policy; unless the policy is to report an error,
+// the scanned value is discarded
+func UnsupportedDecoding(unmarshaller gocoding.Unmarshaller, theType reflect.Type) gocoding.Decoder {
+	if gocoding.OptionsOf(unmarshaller).Unsupported == gocoding.UnsupportedError {
+		return gocoding.ErrorDecoding(gocoding.ErrorPrint("Decoding", "Unsupported type: ", GTTS(theType)))
+	}
+
+	return func(scratch [64]byte, scanner gocoding.Scanner, value reflect.Value) {
+		scanner.NextValue()
+	}
+}
+
 func errorCheck(scanner gocoding.Scanner, err error) {
 	if err == nil {
 		return
@@ -69,16 +115,53 @@ func errorCheck(scanner gocoding.Scanner, err error) {
 	scanner.Error(gocoding.ErrorPrint("Decoding", "An error occured while decoding: ", err.Error()))
 }
 
+// textUnmarshallerDecoder passes the contents of a string to UnmarshalText;
+// the string is read as a value, so that it is unquoted whether it is scanned
+// from text or rescanned
 func textUnmarshallerDecoder(scratch [64]byte, scanner gocoding.Scanner, value reflect.Value) {
 	tuvalue := value.Interface().(encoding.TextUnmarshaler)
-	scanner.Continue()
-	text := scanner.NextString()
-	err := tuvalue.UnmarshalText([]byte(text))
+	text := scanner.NextValue()
+	switch {
+	case !text.IsValid(), text.Kind() == reflect.Interface && text.IsNil():
+		return
+
+	case text.Kind() != reflect.String:
+		scanner.Error(gocoding.ErrorPrintf("Decoding", "Scanned %s while unmarshalling %s", GVTS(text), GVTS(value)))
+		return
+	}
+
+	err := tuvalue.UnmarshalText([]byte(text.String()))
 	if err != nil {
 		scanner.Error(gocoding.ErrorPrint("Text Unmarshal", err))
 	}
 }
 
+// BinaryUnmarshallerDecoding restores the binary form of a value from a string,
+// using the unmarshaller's byte encoding
+func BinaryUnmarshallerDecoding(unmarshaller gocoding.Unmarshaller, theType reflect.Type) gocoding.Decoder {
+	byteEncoding := gocoding.OptionsOf(unmarshaller).ByteEncoding
+
+	return func(scratch [64]byte, scanner gocoding.Scanner, value reflect.Value) {
+		data, ok := decodeBytes(scanner, byteEncoding)
+		if !ok {
+			if theType.Kind() == reflect.Ptr && value.CanSet() {
+				value.Set(reflect.Zero(theType))
+			}
+			return
+		}
+
+		if theType.Kind() == reflect.Ptr && value.IsNil() {
+			value.Set(reflect.New(theType.Elem()))
+		}
+
+		buvalue := value.Interface().(encoding.BinaryUnmarshaler)
+		err := buvalue.UnmarshalBinary(data)
+		if err != nil {
+			scanner.Error(gocoding.ErrorPrint("Binary Unmarshal", err))
+		}
+	}
+}
+
 type decoderType struct {
 	reflect.Type
 }
@@ -97,50 +180,102 @@ func (t decoderType) decode(scratch [64]byte, scanner gocoding.Scanner, value re
 }
 
 func InterfaceDecoding(unmarshaller gocoding.Unmarshaller, theType reflect.Type) gocoding.Decoder {
+	registry := gocoding.OptionsOf(unmarshaller).Types
+	deep := gocoding.OptionsOf(unmarshaller).DecodeMode == gocoding.DecodeDeepMerge
+
 	return func(scratch [64]byte, scanner gocoding.Scanner, value reflect.Value) {
-		fmt.Println("interface decoding", theType, value)
+		if registry != nil && scanner.Peek().Matches(gocoding.ScannedStructBegin, gocoding.ScannedMapBegin) {
+			obj := scanner.NextValue()
+			scanner = gocoding.Rescan(scanner, obj)
+
+			if name, ok := registry.Discriminator(obj); ok {
+				concrete, ok := registry.Type(name)
+				if !ok {
+					scanner.Error(gocoding.ErrorPrintf("Decoding", "%q is not a registered type of %s", name, GTTS(theType)))
+					return
+				}
+
+				if !concrete.AssignableTo(theType) {
+					scanner.Error(gocoding.ErrorPrintf("Decoding", "Registered type %s cannot be assigned to %s", GTTS(concrete), GTTS(theType)))
+					return
+				}
+
+				target := reflect.New(concrete).Elem()
+				unmarshaller.UnmarshalValue(scanner, target)
+				value.Set(target)
+				return
+			}
+		}
+
 		if value.IsNil() {
 			value.Set(scanner.NextValue())
-		} else {
-			unmarshaller.UnmarshalValue(scanner, value.Elem())
+			return
+		}
+
+		elem := value.Elem()
+		if elem.Kind() == reflect.Ptr {
+			unmarshaller.UnmarshalValue(scanner, elem)
+			return
+		}
+
+		// the element cannot be set in place, so decode a copy
+		target := reflect.New(elem.Type()).Elem()
+		if deep {
+			target.Set(elem)
 		}
+		unmarshaller.UnmarshalValue(scanner, target)
+		value.Set(target)
 	}
 }
 
 func StructDecoding(unmarshaller gocoding.Unmarshaller, theType reflect.Type) gocoding.Decoder {
-	decoders := make(map[string]gocoding.Decoder)
+	fields, inline := structFields(theType, gocoding.OptionsOf(unmarshaller), false)
+	decoders := make([]gocoding.Decoder, len(fields))
+	byName := make(map[string]int)
 
-	current := []reflect.Type{}
-	next := []reflect.Type{theType}
+	// fields that must be checked once the struct is decoded
+	defaults := make(map[int]reflect.Value)
+	required := make(map[int]bool)
 
-	for len(next) > 0 {
-		current, next = next, current[:0]
+	for i, field := range fields {
+		decoders[i] = fieldDecoder(unmarshaller, field)
+		byName[field.name] = i
 
-		for _, aType := range current {
-			for i := 0; i < aType.NumField(); i++ {
-				sf := aType.Field(i)
-
-				// skip masked fields
-				if _, ok := decoders[sf.Name]; ok {
-					continue
-				}
-
-				// skip unexported fields
-				if sf.PkgPath != "" {
-					continue
-				}
+		if str, ok := field.tag.Get("default"); ok {
+			defaults[i] = defaultLiteral(field.field.Type, str)
+		}
 
-				// add anonymous fields & skip
-				if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
-					next = append(next, sf.Type)
-					continue
-				}
+		if field.tag.Has("required") {
+			required[i] = true
+		}
+	}
 
-				decoders[sf.Name] = unmarshaller.FindDecoder(sf.Type)
+	// aliases match keys the field was known by, unless another field has
+	// that name
+	for i, field := range fields {
+		for _, option := range field.tag.Options {
+			if _, ok := byName[option.Value]; option.Key == "alias" && !ok {
+				byName[option.Value] = i
 			}
 		}
 	}
 
+	var inlineDecoder gocoding.Decoder
+	reserved := make(map[string]bool)
+	if inline != nil {
+		inlineDecoder = unmarshaller.FindDecoder(inline.field.Type.Elem())
+		for _, key := range reservedKeys(gocoding.OptionsOf(unmarshaller)) {
+			reserved[key] = true
+		}
+	}
+
+	// the struct may decode some keys itself
+	fieldDecodable := gocoding.Implements(theType, fieldDecodableType)
+
+	tracked := len(defaults) > 0 || len(required) > 0
+	presence := gocoding.OptionsOf(unmarshaller).Presence
+	migrations := gocoding.OptionsOf(unmarshaller).Migrations
+
 	return func(scratch [64]byte, scanner gocoding.Scanner, value reflect.Value) {
 		if scanner.Peek() == gocoding.ScannedLiteralBegin {
 			null := scanner.NextValue()
@@ -150,10 +285,31 @@ func StructDecoding(unmarshaller gocoding.Unmarshaller, theType reflect.Type) go
 			}
 		}
 
+		// upgrade old payloads
+		if migrations.Has(theType) && scanner.Peek().Matches(gocoding.ScannedStructBegin, gocoding.ScannedMapBegin) {
+			obj := scanner.NextValue()
+			if err := migrations.Migrate(theType, obj.Interface().(map[string]interface{})); err != nil {
+				scanner.Error(gocoding.ErrorPrint("Decoding", err))
+				return
+			}
+			scanner = gocoding.Rescan(scanner, obj)
+		}
+
 		if !gocoding.PeekCheck(scanner, gocoding.ScannedStructBegin, gocoding.ScannedMapBegin) {
 			return
 		}
 
+		var present []string
+		var seen []bool
+		if tracked {
+			seen = make([]bool, len(fields))
+		}
+
+		var custom gocoding.FieldDecodable
+		if receiving, ok := gocoding.Receive(value, fieldDecodable); ok {
+			custom = receiving.Interface().(gocoding.FieldDecodable)
+		}
+
 		for {
 			// get the next code, check for the end
 			code := scanner.Continue()
@@ -175,43 +331,142 @@ func StructDecoding(unmarshaller gocoding.Unmarshaller, theType reflect.Type) go
 			}
 			keystr := key.String()
 
+			if presence != nil {
+				present = append(present, keystr)
+			}
+
 			// check by name
-			decoder := decoders[keystr]
+			i, ok := byName[keystr]
 
 			// check by case-folded name (disableable?)
-			if decoder == nil {
-				for name, altdec := range decoders {
+			if !ok {
+				for name, j := range byName {
 					if strings.EqualFold(keystr, name) {
-						keystr, decoder = name, altdec
+						i, ok = j, true
 						break
 					}
 				}
 			}
 
 			scanner.Continue()
-			if decoder == nil {
+			if custom != nil {
+				gocoding.EnterElement(scanner, keystr)
+				handled := custom.DecodeField(keystr, unmarshaller, scanner)
+				gocoding.ExitElement(scanner)
+
+				if handled {
+					if ok && tracked {
+						seen[i] = true
+					}
+					continue
+				}
+			}
+
+			if !ok && inlineDecoder != nil && !reserved[keystr] {
+				// collect unknown keys in the inline map
+				extra, ok := fieldByIndexAlloc(value, inline.index)
+				if !ok {
+					scanner.Error(gocoding.ErrorPrintf("Decoding", "Cannot set embedded pointer to unexported struct while unmarshalling %s", GTTS(theType)))
+					return
+				}
+				if extra.IsNil() {
+					extra.Set(reflect.MakeMap(extra.Type()))
+				}
+
+				elem := reflect.New(extra.Type().Elem()).Elem()
+				gocoding.EnterElement(scanner, keystr)
+				inlineDecoder(scratch, scanner, elem)
+				gocoding.ExitElement(scanner)
+				extra.SetMapIndex(reflect.ValueOf(keystr).Convert(extra.Type().Key()), elem)
+				continue
+			}
+
+			if !ok {
 				scanner.NextValue()
-			} else {
-				decoder(scratch, scanner, value.FieldByName(keystr))
+				continue
+			}
+
+			if tracked {
+				seen[i] = true
+			}
+			field, ok := fieldByIndexAlloc(value, fields[i].index)
+			if !ok {
+				scanner.Error(gocoding.ErrorPrintf("Decoding", "Cannot set embedded pointer to unexported struct while unmarshalling %s", GTTS(theType)))
+				return
+			}
+
+			gocoding.EnterElement(scanner, fields[i].name)
+			decoders[i](scratch, scanner, field)
+			gocoding.ExitElement(scanner)
+		}
+
+		// fill in defaults, check for required fields; a default does not
+		// replace a value the target already holds, such as a merged config
+		for i := 0; tracked && i < len(fields); i++ {
+			if seen[i] {
+				continue
+			}
+
+			if literal, ok := defaults[i]; ok {
+				if field, ok := fieldByIndex(value, fields[i].index); ok && !field.IsZero() {
+					continue
+				}
+
+				field, ok := fieldByIndexAlloc(value, fields[i].index)
+				if !ok {
+					continue
+				}
+
+				gocoding.EnterElement(scanner, fields[i].name)
+				decoders[i](scratch, gocoding.Rescan(scanner, literal), field)
+				gocoding.ExitElement(scanner)
+			} else if required[i] {
+				scanner.Error(gocoding.ErrorPrintf("Decoding", "Missing required key %s while unmarshalling %s", fields[i].name, GTTS(theType)))
 			}
 		}
+
+		if presence != nil {
+			presence(value, present)
+		}
+	}
+}
+
+// fieldDecoder finds the decoder for a struct field, taking its tag into
+// account
+func fieldDecoder(unmarshaller gocoding.Unmarshaller, field *structField) gocoding.Decoder {
+	if decoder := timeFieldDecoder(field); decoder != nil {
+		return decoder
+	}
+
+	if decoder := setFieldDecoder(unmarshaller, field); decoder != nil {
+		return decoder
 	}
+
+	return unmarshaller.FindDecoder(field.field.Type)
 }
 
 func MapDecoding(unmarshaller gocoding.Unmarshaller, theType reflect.Type) gocoding.Decoder {
+	if gocoding.OptionsOf(unmarshaller).Sets && IsSetType(theType) {
+		return SetDecoding(unmarshaller, theType)
+	}
+
+	return mapDecoding(unmarshaller, theType)
+}
+
+func mapDecoding(unmarshaller gocoding.Unmarshaller, theType reflect.Type) gocoding.Decoder {
 	if theType.Key().Kind() != reflect.String {
 		return gocoding.ErrorDecoding(gocoding.ErrorPrint("Decoding", "Unsupported map key type: ", theType.Key()))
 	}
 
 	elemType := theType.Elem()
 	decoder := unmarshaller.FindDecoder(elemType)
-	ptrDecoder := unmarshaller.FindDecoder(reflect.PtrTo(elemType))
 	if decoder == nil {
 		return nil
 	}
 
-	return func(scratch [64]byte, scanner gocoding.Scanner, value reflect.Value) {
+	deep := gocoding.OptionsOf(unmarshaller).DecodeMode == gocoding.DecodeDeepMerge
 
+	return func(scratch [64]byte, scanner gocoding.Scanner, value reflect.Value) {
 		if scanner.Peek() == gocoding.ScannedLiteralBegin {
 			null := scanner.NextValue()
 			if null.IsValid() && null.IsNil() {
@@ -248,16 +503,18 @@ func MapDecoding(unmarshaller gocoding.Unmarshaller, theType reflect.Type) gocod
 				scanner.Error(gocoding.ErrorPrint("Decoding", "Invalid key type %s", key.Type().String()))
 			}
 
-			elem := value.MapIndex(key)
 			scanner.Continue()
 
-			if elem.IsValid() {
-				decoder(scratch, scanner, elem)
-			} else {
-				elem = reflect.New(elemType)
-				ptrDecoder(scratch, scanner, elem)
-				value.SetMapIndex(key, elem.Elem())
+			// map elements cannot be set in place, so decode a copy
+			elem := reflect.New(elemType).Elem()
+			if existing := value.MapIndex(key); deep && existing.IsValid() {
+				elem.Set(existing)
 			}
+
+			gocoding.EnterElement(scanner, key.String())
+			decoder(scratch, scanner, elem)
+			gocoding.ExitElement(scanner)
+			value.SetMapIndex(key, elem)
 		}
 	}
 }
@@ -268,6 +525,9 @@ func ArrayDecoding(unmarshaller gocoding.Unmarshaller, theType reflect.Type) goc
 		return nil
 	}
 
+	deep := gocoding.OptionsOf(unmarshaller).DecodeMode == gocoding.DecodeDeepMerge
+	zero := reflect.Zero(theType.Elem())
+
 	return func(scratch [64]byte, scanner gocoding.Scanner, value reflect.Value) {
 		if scanner.Peek() == gocoding.ScannedLiteralBegin {
 			null := scanner.NextValue()
@@ -281,7 +541,8 @@ func ArrayDecoding(unmarshaller gocoding.Unmarshaller, theType reflect.Type) goc
 			return
 		}
 
-		for i := 0; true; i++ {
+		i := 0
+		for ; true; i++ {
 			// get the next code, check for the end
 			code := scanner.Continue()
 			if code.Matches(gocoding.ScannedArrayEnd) {
@@ -289,16 +550,29 @@ func ArrayDecoding(unmarshaller gocoding.Unmarshaller, theType reflect.Type) goc
 			}
 
 			// decode until full, skip any excess entries
-			if i < value.Len() {
-				decoder(scratch, scanner, value.Index(i))
+			if i >= value.Len() {
+				scanner.NextValue()
+				continue
+			}
+
+			if !deep {
+				value.Index(i).Set(zero)
 			}
+			gocoding.EnterIndex(scanner, i)
+			decoder(scratch, scanner, value.Index(i))
+			gocoding.ExitElement(scanner)
+		}
+
+		// clear any remaining entries
+		for ; !deep && i < value.Len(); i++ {
+			value.Index(i).Set(zero)
 		}
 	}
 }
 
 func SliceDecoding(unmarshaller gocoding.Unmarshaller, theType reflect.Type) gocoding.Decoder {
 	if theType.Elem().Kind() == reflect.Uint8 {
-		return ByteSliceDecoder
+		return ByteSliceDecoding(unmarshaller, theType)
 	}
 
 	decoder := unmarshaller.FindDecoder(theType.Elem())
@@ -306,6 +580,9 @@ func SliceDecoding(unmarshaller gocoding.Unmarshaller, theType reflect.Type) goc
 		return nil
 	}
 
+	deep := gocoding.OptionsOf(unmarshaller).DecodeMode == gocoding.DecodeDeepMerge
+	zero := reflect.Zero(theType.Elem())
+
 	return func(scratch [64]byte, scanner gocoding.Scanner, value reflect.Value) {
 		if scanner.Peek() == gocoding.ScannedLiteralBegin {
 			null := scanner.NextValue()
@@ -319,7 +596,8 @@ func SliceDecoding(unmarshaller gocoding.Unmarshaller, theType reflect.Type) goc
 			return
 		}
 
-		for i := 0; true; i++ {
+		i := 0
+		for ; true; i++ {
 			// get the next code, check for the end
 			code := scanner.Continue()
 			if code.Matches(gocoding.ScannedArrayEnd) {
@@ -340,13 +618,25 @@ func SliceDecoding(unmarshaller gocoding.Unmarshaller, theType reflect.Type) goc
 
 			if i >= value.Len() {
 				value.SetLen(i + 1)
+				value.Index(i).Set(zero)
+			} else if !deep {
+				value.Index(i).Set(zero)
 			}
 
+			gocoding.EnterIndex(scanner, i)
 			decoder(scratch, scanner, value.Index(i))
+			gocoding.ExitElement(scanner)
 		}
+
+		// drop any remaining elements
+		value.SetLen(i)
 	}
 }
 
+// ByteSliceDecoder reads byte slices from hex strings.
+//
+// Deprecated: use ByteSliceDecoding, which follows the unmarshaller's byte
+// encoding.
 func ByteSliceDecoder(scratch [64]byte, scanner gocoding.Scanner, value reflect.Value) {
 	bytes := scanner.NextValue()
 	switch bytes.Kind() {
@@ -364,13 +654,103 @@ func ByteSliceDecoder(scratch [64]byte, scanner gocoding.Scanner, value reflect.
 	}
 }
 
+// ByteSliceDecoding reads byte slices from strings, using the unmarshaller's
+// byte encoding
+func ByteSliceDecoding(unmarshaller gocoding.Unmarshaller, theType reflect.Type) gocoding.Decoder {
+	byteEncoding := gocoding.OptionsOf(unmarshaller).ByteEncoding
+
+	return func(scratch [64]byte, scanner gocoding.Scanner, value reflect.Value) {
+		data, ok := decodeBytes(scanner, byteEncoding)
+		if !ok {
+			value.Set(reflect.Zero(theType))
+			return
+		}
+
+		value.SetBytes(data)
+	}
+}
+
+// ByteArrayDecoding reads byte arrays from strings, using the unmarshaller's
+// byte encoding; the decoded data must fill the array exactly
+func ByteArrayDecoding(unmarshaller gocoding.Unmarshaller, theType reflect.Type) gocoding.Decoder {
+	byteEncoding := gocoding.OptionsOf(unmarshaller).ByteEncoding
+
+	return func(scratch [64]byte, scanner gocoding.Scanner, value reflect.Value) {
+		data, ok := decodeBytes(scanner, byteEncoding)
+		if !ok {
+			value.Set(reflect.Zero(theType))
+			return
+		}
+
+		if len(data) != theType.Len() {
+			scanner.Error(gocoding.ErrorPrintf("Decoding", "Decoding %s: expected %d bytes, got %d", GTTS(theType), theType.Len(), len(data)))
+			return
+		}
+
+		for i, b := range data {
+			value.Index(i).SetUint(uint64(b))
+		}
+	}
+}
+
+// scan a byte string; returns false if the value was null
+func decodeBytes(scanner gocoding.Scanner, byteEncoding gocoding.ByteEncoding) ([]byte, bool) {
+	bytes := scanner.NextValue()
+	switch bytes.Kind() {
+	case reflect.Invalid:
+		return nil, false
+
+	case reflect.Interface:
+		if bytes.IsNil() {
+			return nil, false
+		}
+
+	case reflect.String:
+		data, err := byteEncoding.DecodeString(bytes.String())
+		errorCheck(scanner, err)
+		return data, true
+	}
+
+	scanner.Error(gocoding.ErrorPrintf("Decoding", "Decoding bytes: expected String, got %s", bytes.Type().String()))
+	return nil, false
+}
+
 func PtrDecoding(unmarshaller gocoding.Unmarshaller, theType reflect.Type) gocoding.Decoder {
 	decoder := unmarshaller.FindDecoder(theType.Elem())
 	if decoder == nil {
 		return nil
 	}
 
+	references := gocoding.OptionsOf(unmarshaller).References == gocoding.ReferencesPreserve && theType.Elem().Kind() == reflect.Struct
+
 	return func(scratch [64]byte, scanner gocoding.Scanner, value reflect.Value) {
+		if references && scanner.Peek().Matches(gocoding.ScannedStructBegin, gocoding.ScannedMapBegin) {
+			obj := scanner.NextValue()
+
+			if id, ok := gocoding.ReferenceKey(obj, gocoding.RefKey); ok {
+				pointer, ok := gocoding.Reference(scanner, id)
+				if !ok {
+					scanner.Error(gocoding.ErrorPrintf("Decoding", "Unknown reference %s", id))
+				} else if pointer.Type() != theType {
+					scanner.Error(gocoding.ErrorPrintf("Decoding", "Reference %s is a %s, not a %s", id, GVTS(pointer), GTTS(theType)))
+				} else {
+					value.Set(pointer)
+				}
+				return
+			}
+
+			if value.IsNil() {
+				value.Set(reflect.New(theType.Elem()))
+			}
+
+			if id, ok := gocoding.ReferenceKey(obj, gocoding.IDKey); ok {
+				gocoding.SetReference(scanner, id, value)
+			}
+
+			decoder(scratch, gocoding.Rescan(scanner, obj), value.Elem())
+			return
+		}
+
 		if value.IsNil() {
 			value.Set(reflect.New(theType.Elem()))
 		}
@@ -6,24 +6,47 @@ import (
 	"github.com/FactomProject/gocoding"
 	"math"
 	"reflect"
+	"sort"
 	"strconv"
 )
 
 var encodableType1 = reflect.TypeOf(new(gocoding.Encodable1)).Elem()
 var encodableType2 = reflect.TypeOf(new(gocoding.Encodable2)).Elem()
+var encodableType3 = reflect.TypeOf(new(gocoding.Encodable3)).Elem()
 var textMarshallerType = reflect.TypeOf(new(encoding.TextMarshaler)).Elem()
+var binaryMarshallerType = reflect.TypeOf(new(encoding.BinaryMarshaler)).Elem()
 
 func Encoding(marshaller gocoding.Marshaller, theType reflect.Type) gocoding.Encoder {
-	if theType.ConvertibleTo(encodableType1) {
+	if Builtin(theType) {
+		return builtinEncoding(marshaller, theType)
+	}
+
+	if gocoding.IsOptional(theType) {
+		return gocoding.OptionalEncoding(marshaller, theType)
+	}
+
+	if enum, ok := gocoding.LookupEnum(theType); ok {
+		return EnumEncoding(marshaller, enum)
+	}
+
+	if gocoding.Implements(theType, encodableType1) == gocoding.ValueReceiver {
 		return Encodable1Encoding(marshaller, theType)
 	}
 
-	if theType.ConvertibleTo(encodableType2) {
+	if gocoding.Implements(theType, encodableType3) == gocoding.ValueReceiver {
+		return Encodable3Encoding(marshaller, theType)
+	}
+
+	if gocoding.Implements(theType, encodableType2) == gocoding.ValueReceiver {
 		return Encodable2Encoding(marshaller, theType)
 	}
 
-	if theType.ConvertibleTo(textMarshallerType) {
-		return textMarshallerEncoder
+	if gocoding.Implements(theType, textMarshallerType) == gocoding.ValueReceiver {
+		return gocoding.HookEncoder(gocoding.ValueReceiver, textMarshallerEncoder, nil)
+	}
+
+	if gocoding.Implements(theType, binaryMarshallerType) == gocoding.ValueReceiver {
+		return BinaryMarshallerEncoding(marshaller, theType)
 	}
 
 	var encoder gocoding.Encoder
@@ -47,7 +70,11 @@ func Encoding(marshaller gocoding.Marshaller, theType reflect.Type) gocoding.Enc
 		encoder = stringEncoder
 
 	case reflect.Interface:
-		encoder = InterfaceEncoding(marshaller, theType)
+		if theType.Implements(readerType) {
+			encoder = ReaderEncoding(marshaller, theType)
+		} else {
+			encoder = InterfaceEncoding(marshaller, theType)
+		}
 
 	case reflect.Struct:
 		encoder = StructEncoding(marshaller, theType)
@@ -59,63 +86,110 @@ func Encoding(marshaller gocoding.Marshaller, theType reflect.Type) gocoding.Enc
 		encoder = SliceEncoding(marshaller, theType)
 
 	case reflect.Array:
-		encoder = ArrayEncoding(marshaller, theType)
+		if theType.Elem().Kind() == reflect.Uint8 {
+			encoder = ByteArrayEncoding(marshaller, theType)
+		} else {
+			encoder = ArrayEncoding(marshaller, theType)
+		}
 
 	case reflect.Ptr:
 		encoder = PtrEncoding(marshaller, theType)
 
+	case reflect.Chan:
+		encoder = ChanEncoding(marshaller, theType)
+
+	case reflect.Func:
+		if IsSeqType(theType) {
+			encoder = SeqEncoding(marshaller, theType)
+		} else {
+			encoder = UnsupportedEncoding(marshaller, theType)
+		}
+
 	default:
-		encoder = errorEncoding(gocoding.ErrorPrint("Encoding", "Unsupported type: ", theType))
+		encoder = UnsupportedEncoding(marshaller, theType)
 	}
 
-	if theType.Kind() == reflect.Ptr {
-		return encoder
+	// encode addressable values with the pointer's hooks
+	for _, iface := range encodingHooks {
+		if gocoding.Implements(theType, iface) == gocoding.PointerReceiver {
+			return gocoding.HookEncoder(gocoding.PointerReceiver, marshaller.FindEncoder(reflect.PtrTo(theType)), encoder)
+		}
 	}
 
-	ptrType := reflect.PtrTo(theType)
-	if ptrType.ConvertibleTo(encodableType1) ||
-		ptrType.ConvertibleTo(encodableType2) ||
-		ptrType.ConvertibleTo(textMarshallerType) {
-		return tryIndirectEncoder(encoder, marshaller.FindEncoder(ptrType))
-	} else {
-		return encoder
-	}
+	return encoder
 }
 
+// the interfaces that replace the encoding of a type
+var encodingHooks = []reflect.Type{encodableType1, encodableType2, encodableType3, textMarshallerType, binaryMarshallerType}
+
 func errorEncoding(err *gocoding.Error) gocoding.Encoder {
 	return func(scratch [64]byte, renderer gocoding.Renderer, value reflect.Value) {
 		renderer.Error(err)
 	}
 }
 
-func Encodable1Encoding(marshaller gocoding.Marshaller, theType reflect.Type) gocoding.Encoder {
-	return func(scratch [64]byte, renderer gocoding.Renderer, value reflect.Value) {
-		if value.IsNil() {
+// UnsupportedEncoding handles types that cannot be encoded, according to the
+// marshaller's unsupported policy
+func UnsupportedEncoding(marshaller gocoding.Marshaller, theType reflect.Type) gocoding.Encoder {
+	switch gocoding.OptionsOf(marshaller).Unsupported {
+	case gocoding.UnsupportedSkip, gocoding.UnsupportedNull:
+		return func(scratch [64]byte, renderer gocoding.Renderer, value reflect.Value) {
 			renderer.WriteNil()
-		} else {
-			encoder := value.Interface().(gocoding.Encodable1).Encoding(marshaller, theType)
-			if encoder == nil {
-				return
-			}
-			encoder(scratch, renderer, value)
 		}
+
+	case gocoding.UnsupportedPlaceholder:
+		placeholder := "<" + theType.String() + ">"
+		return func(scratch [64]byte, renderer gocoding.Renderer, value reflect.Value) {
+			renderer.PrintString(placeholder)
+		}
+
+	default:
+		return errorEncoding(gocoding.ErrorPrint("Encoding", "Unsupported type: ", theType))
 	}
 }
 
+func Encodable1Encoding(marshaller gocoding.Marshaller, theType reflect.Type) gocoding.Encoder {
+	return gocoding.HookEncoder(gocoding.ValueReceiver, func(scratch [64]byte, renderer gocoding.Renderer, value reflect.Value) {
+		encoder := value.Interface().(gocoding.Encodable1).Encoding(marshaller, theType)
+		if encoder == nil {
+			return
+		}
+		encoder(scratch, renderer, value)
+	}, nil)
+}
+
 func Encodable2Encoding(marshaller gocoding.Marshaller, theType reflect.Type) gocoding.Encoder {
-	return func(scratch [64]byte, renderer gocoding.Renderer, value reflect.Value) {
-		if value.IsNil() {
-			renderer.WriteNil()
-		} else {
-			renderer.StartStruct()
-			for name, value := range value.Interface().(gocoding.Encodable2).EncodableFields() {
-				renderer.StartElement(name)
-				marshaller.MarshalValue(renderer, value)
-				renderer.StopElement(name)
+	return gocoding.HookEncoder(gocoding.ValueReceiver, func(scratch [64]byte, renderer gocoding.Renderer, value reflect.Value) {
+		renderer.StartStruct()
+		for name, value := range value.Interface().(gocoding.Encodable2).EncodableFields() {
+			renderer.StartElement(name)
+			marshaller.MarshalValue(renderer, value)
+			renderer.StopElement(name)
+		}
+		renderer.StopStruct()
+	}, nil)
+}
+
+// Encodable3Encoding writes the fields of an Encodable3 in order, leaving out
+// empty fields with the omitempty option
+func Encodable3Encoding(marshaller gocoding.Marshaller, theType reflect.Type) gocoding.Encoder {
+	return gocoding.HookEncoder(gocoding.ValueReceiver, func(scratch [64]byte, renderer gocoding.Renderer, value reflect.Value) {
+		renderer.StartStruct()
+		for _, field := range value.Interface().(gocoding.Encodable3).EncodableFieldList() {
+			if field.Has("omitempty") && gocoding.IsEmpty(field.Value) {
+				continue
 			}
-			renderer.StopStruct()
+
+			renderer.StartElement(field.Name)
+			if field.Encoder != nil {
+				field.Encoder(scratch, renderer, field.Value)
+			} else {
+				marshaller.MarshalValue(renderer, field.Value)
+			}
+			renderer.StopElement(field.Name)
 		}
-	}
+		renderer.StopStruct()
+	}, nil)
 }
 
 func textMarshallerEncoder(scratch [64]byte, renderer gocoding.Renderer, value reflect.Value) {
@@ -127,14 +201,20 @@ func textMarshallerEncoder(scratch [64]byte, renderer gocoding.Renderer, value r
 	renderer.Write(text)
 }
 
-func tryIndirectEncoder(typEncoder, ptrEncoder gocoding.Encoder) gocoding.Encoder {
-	return func(scratch [64]byte, renderer gocoding.Renderer, value reflect.Value) {
-		if value.CanAddr() {
-			ptrEncoder(scratch, renderer, value.Addr())
-		} else {
-			typEncoder(scratch, renderer, value)
+// BinaryMarshallerEncoding writes the binary form of a value as a string, using
+// the marshaller's byte encoding
+func BinaryMarshallerEncoding(marshaller gocoding.Marshaller, theType reflect.Type) gocoding.Encoder {
+	byteEncoding := gocoding.OptionsOf(marshaller).ByteEncoding
+
+	return gocoding.HookEncoder(gocoding.ValueReceiver, func(scratch [64]byte, renderer gocoding.Renderer, value reflect.Value) {
+		bmvalue := value.Interface().(encoding.BinaryMarshaler)
+		data, err := bmvalue.MarshalBinary()
+		if err != nil {
+			renderer.Error(gocoding.ErrorPrint("Binary Marshal", err))
+			return
 		}
-	}
+		renderer.PrintString(byteEncoding.EncodeToString(data))
+	}, nil)
 }
 
 func boolEncoder(scratch [64]byte, renderer gocoding.Renderer, value reflect.Value) {
@@ -174,64 +254,134 @@ func stringEncoder(scratch [64]byte, renderer gocoding.Renderer, value reflect.V
 }
 
 func InterfaceEncoding(marshaller gocoding.Marshaller, theType reflect.Type) gocoding.Encoder {
+	registry := gocoding.OptionsOf(marshaller).Types
+
 	return func(scratch [64]byte, renderer gocoding.Renderer, value reflect.Value) {
 		if value.IsNil() {
 			renderer.WriteNil()
 			return
 		}
 
-		marshaller.MarshalValue(renderer, value.Elem())
+		elem := value.Elem()
+		if registry == nil {
+			marshaller.MarshalValue(renderer, elem)
+			return
+		}
+
+		name, ok := registry.Name(elem.Type())
+		if !ok || elem.Kind() == reflect.Ptr && elem.IsNil() {
+			marshaller.MarshalValue(renderer, elem)
+			return
+		}
+
+		// the discriminator can only be written into an object
+		prefixed := gocoding.PrefixElement(renderer, registry.Key, name)
+		marshaller.MarshalValue(prefixed, elem)
+		if !gocoding.Prefixed(prefixed) {
+			renderer.Error(gocoding.ErrorPrintf("Encoding", "Cannot write the %s of %s: it is not encoded as an object", registry.Key, elem.Type()))
+		}
 	}
 }
 
 func StructEncoding(marshaller gocoding.Marshaller, theType reflect.Type) gocoding.Encoder {
-	encoders := make(map[string]gocoding.Encoder)
+	fields, inline := structFields(theType, gocoding.OptionsOf(marshaller), true)
+	encoders := make([]gocoding.Encoder, len(fields))
+	optional := make([]bool, len(fields))
+	unexported := make([]bool, len(fields))
+	names := make(map[string]bool)
+
+	// unexported fields that are not scalars are read through their address
+	addressable := false
+
+	for i, field := range fields {
+		encoders[i] = fieldEncoder(marshaller, field)
+		optional[i] = gocoding.IsOptional(field.field.Type)
+		unexported[i] = field.field.PkgPath != ""
+		names[field.name] = true
+		addressable = addressable || unexported[i] && readsUnexported(field.field.Type.Kind())
+	}
 
-	current := []reflect.Type{}
-	next := []reflect.Type{theType}
+	var inlineEncoder gocoding.Encoder
+	if inline != nil {
+		inlineEncoder = marshaller.FindEncoder(inline.field.Type.Elem())
+		for _, key := range reservedKeys(gocoding.OptionsOf(marshaller)) {
+			names[key] = true
+		}
+	}
 
-	for len(next) > 0 {
-		current, next = next, current[:0]
+	return func(scratch [64]byte, renderer gocoding.Renderer, value reflect.Value) {
+		if addressable && !value.CanAddr() {
+			copied := reflect.New(theType).Elem()
+			copied.Set(value)
+			value = copied
+		}
 
-		for _, aType := range current {
-			for i := 0; i < aType.NumField(); i++ {
-				sf := aType.Field(i)
+		renderer.StartStruct()
 
-				// skip masked fields
-				if _, ok := encoders[sf.Name]; ok {
-					continue
-				}
+		for i, field := range fields {
+			fieldValue, ok := fieldByIndex(value, field.index)
+			if !ok || optional[i] && gocoding.IsUnset(fieldValue) {
+				continue
+			}
 
-				// skip unexported fields
-				if sf.PkgPath != "" {
-					continue
-				}
+			if unexported[i] {
+				fieldValue = readable(fieldValue)
+			}
 
-				// add anonymous fields & skip
-				if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
-					next = append(next, sf.Type)
-					continue
-				}
+			renderer.StartElement(field.name)
+			encoders[i](scratch, renderer, fieldValue)
+			renderer.StopElement(field.name)
+		}
 
-				encoders[sf.Name] = marshaller.FindEncoder(sf.Type)
-			}
+		if inlineEncoder != nil {
+			encodeInline(scratch, renderer, value, inline, inlineEncoder, names)
 		}
+
+		renderer.StopStruct()
 	}
+}
 
-	return func(scratch [64]byte, renderer gocoding.Renderer, value reflect.Value) {
-		renderer.StartStruct()
+// encodeInline flattens an inline map into its struct; fields and reserved keys
+// take precedence
+func encodeInline(scratch [64]byte, renderer gocoding.Renderer, value reflect.Value, inline *structField, encoder gocoding.Encoder, names map[string]bool) {
+	extra, ok := fieldByIndex(value, inline.index)
+	if !ok {
+		return
+	}
 
-		for name, encoder := range encoders {
-			renderer.StartElement(name)
-			encoder(scratch, renderer, value.FieldByName(name))
-			renderer.StopElement(name)
+	keys := extra.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+	for _, key := range keys {
+		if names[key.String()] {
+			continue
 		}
 
-		renderer.StopStruct()
+		renderer.StartElement(key.String())
+		encoder(scratch, renderer, extra.MapIndex(key))
+		renderer.StopElement(key.String())
 	}
 }
 
+// fieldEncoder finds the encoder for a struct field, taking its tag into
+// account
+func fieldEncoder(marshaller gocoding.Marshaller, field *structField) gocoding.Encoder {
+	if encoder := timeFieldEncoder(field); encoder != nil {
+		return encoder
+	}
+
+	if encoder := setFieldEncoder(marshaller, field); encoder != nil {
+		return encoder
+	}
+
+	return marshaller.FindEncoder(field.field.Type)
+}
+
 func MapEncoding(marshaller gocoding.Marshaller, theType reflect.Type) gocoding.Encoder {
+	if gocoding.OptionsOf(marshaller).Sets && IsSetType(theType) {
+		return SetEncoding(marshaller, theType)
+	}
+
 	if theType.Key().Kind() != reflect.String {
 		return errorEncoding(gocoding.ErrorPrint("Encoding", "Unsupported map key type: ", theType.Key()))
 	}
@@ -261,7 +411,7 @@ func MapEncoding(marshaller gocoding.Marshaller, theType reflect.Type) gocoding.
 
 func SliceEncoding(marshaller gocoding.Marshaller, theType reflect.Type) gocoding.Encoder {
 	if theType.Elem().Kind() == reflect.Uint8 {
-		return ByteSliceEncoder
+		return ByteSliceEncoding(marshaller, theType)
 	}
 
 	encoder := ArrayEncoding(marshaller, theType)
@@ -279,6 +429,10 @@ func SliceEncoding(marshaller gocoding.Marshaller, theType reflect.Type) gocodin
 	}
 }
 
+// ByteSliceEncoder writes byte slices as hex strings.
+//
+// Deprecated: use ByteSliceEncoding, which follows the marshaller's byte
+// encoding.
 func ByteSliceEncoder(scratch [64]byte, renderer gocoding.Renderer,
 	value reflect.Value) {
 	if value.IsNil() {
@@ -298,6 +452,40 @@ func ByteSliceEncoder(scratch [64]byte, renderer gocoding.Renderer,
 	renderer.Print(`"`)
 }
 
+// ByteSliceEncoding writes byte slices as strings, using the marshaller's byte
+// encoding
+func ByteSliceEncoding(marshaller gocoding.Marshaller, theType reflect.Type) gocoding.Encoder {
+	byteEncoding := gocoding.OptionsOf(marshaller).ByteEncoding
+
+	return func(scratch [64]byte, renderer gocoding.Renderer, value reflect.Value) {
+		if value.IsNil() {
+			renderer.WriteNil()
+			return
+		}
+
+		renderer.PrintString(byteEncoding.EncodeToString(value.Bytes()))
+	}
+}
+
+// ByteArrayEncoding writes byte arrays as strings, using the marshaller's byte
+// encoding
+func ByteArrayEncoding(marshaller gocoding.Marshaller, theType reflect.Type) gocoding.Encoder {
+	byteEncoding := gocoding.OptionsOf(marshaller).ByteEncoding
+
+	return func(scratch [64]byte, renderer gocoding.Renderer, value reflect.Value) {
+		renderer.PrintString(byteEncoding.EncodeToString(arrayBytes(value)))
+	}
+}
+
+// copy the contents of a byte array, which may not be addressable
+func arrayBytes(value reflect.Value) []byte {
+	bytes := make([]byte, value.Len())
+	for i := range bytes {
+		bytes[i] = byte(value.Index(i).Uint())
+	}
+	return bytes
+}
+
 func ArrayEncoding(marshaller gocoding.Marshaller,
 	theType reflect.Type) gocoding.Encoder {
 	encoder := marshaller.FindEncoder(theType.Elem())
@@ -331,8 +519,15 @@ func PtrEncoding(marshaller gocoding.Marshaller,
 	return func(scratch [64]byte, renderer gocoding.Renderer, value reflect.Value) {
 		if value.IsNil() {
 			renderer.WriteNil()
-		} else {
-			encoder(scratch, renderer, value.Elem())
+			return
 		}
+
+		renderer, ok := gocoding.EnterPointer(renderer, value)
+		if !ok {
+			return
+		}
+
+		encoder(scratch, renderer, value.Elem())
+		gocoding.ExitPointer(renderer, value)
 	}
 }
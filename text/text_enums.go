@@ -0,0 +1,101 @@
+package text
+
+import (
+	"github.com/FactomProject/gocoding"
+	"reflect"
+	"strconv"
+)
+
+// EnumEncoding writes the name of a registered enum value, or the array of the
+// names of the bits of a registered flag value
+func EnumEncoding(marshaller gocoding.Marshaller, enum *gocoding.Enum) gocoding.Encoder {
+	if enum.Flags {
+		return func(scratch [64]byte, renderer gocoding.Renderer, value reflect.Value) {
+			names, ok := enum.Names(value)
+			if !ok {
+				renderer.Error(gocoding.ErrorPrintf("Encoding", "%s has bits that are not registered: %#x", enum.Type, value.Interface()))
+				return
+			}
+
+			renderer.StartArray()
+			for i, name := range names {
+				id := strconv.Itoa(i)
+				renderer.StartElement(id)
+				renderer.PrintString(name)
+				renderer.StopElement(id)
+			}
+			renderer.StopArray()
+		}
+	}
+
+	return func(scratch [64]byte, renderer gocoding.Renderer, value reflect.Value) {
+		name, ok := enum.Name(value)
+		if !ok {
+			renderer.Error(gocoding.ErrorPrintf("Encoding", "%v is not a registered %s", value.Interface(), enum.Type))
+			return
+		}
+
+		renderer.PrintString(name)
+	}
+}
+
+// EnumDecoding reads a registered enum value from its name, or a registered
+// flag value from the array of the names of its bits. Numbers are accepted if
+// they are registered values, so that payloads written before the type was
+// registered can still be read.
+func EnumDecoding(unmarshaller gocoding.Unmarshaller, enum *gocoding.Enum) gocoding.Decoder {
+	return func(scratch [64]byte, scanner gocoding.Scanner, value reflect.Value) {
+		scanned := scanner.NextValue()
+		if scanned.Kind() == reflect.Interface && !scanned.IsNil() {
+			scanned = scanned.Elem()
+		}
+
+		switch scanned.Kind() {
+		case reflect.String:
+			if !enum.Flags && enum.Set(value, scanned.String()) {
+				return
+			}
+
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if enum.SetNumber(value, scanned.Int()) {
+				return
+			}
+
+		case reflect.Float32, reflect.Float64:
+			number := scanned.Float()
+			if number == float64(int64(number)) && enum.SetNumber(value, int64(number)) {
+				return
+			}
+
+		case reflect.Slice:
+			if !enum.Flags {
+				break
+			}
+
+			names := make([]string, scanned.Len())
+			for i := range names {
+				name := scanned.Index(i)
+				if name.Kind() == reflect.Interface && !name.IsNil() {
+					name = name.Elem()
+				}
+				if name.Kind() != reflect.String {
+					scanner.Error(gocoding.ErrorPrintf("Decoding", "Scanned %s in the flags of %s", name.Type(), enum.Type))
+					return
+				}
+				names[i] = name.String()
+			}
+
+			if name, ok := enum.SetNames(value, names); !ok {
+				scanner.Error(gocoding.ErrorPrintf("Decoding", "%q is not a registered %s", name, enum.Type))
+			}
+			return
+		}
+
+		if !scanned.IsValid() || scanned.Kind() == reflect.Interface {
+			scanner.Error(gocoding.ErrorPrintf("Decoding", "Scanned null while unmarshalling %s", enum.Type))
+			return
+		}
+
+		scanner.Error(gocoding.ErrorPrintf("Decoding", "%#v is not a registered %s", scanned.Interface(), enum.Type))
+	}
+}
@@ -0,0 +1,300 @@
+package text
+
+import (
+	"github.com/FactomProject/gocoding"
+	"reflect"
+	"sort"
+	"strconv"
+	"unsafe"
+)
+
+type structField struct {
+	name  string
+	index []int
+	field reflect.StructField
+	tag   gocoding.Tag
+}
+
+// structFields lists the fields of a struct type that are encoded, or decoded,
+// in declaration order, including unexported fields if they are encoded and
+// the marshaller is configured to. Fields are named by their
+// tag or by the marshaller's naming. As in
+// encoding/json, the fields of embedded structs and pointers to
+// structs are promoted unless the embedded field is tagged with a name, and
+// embedded interfaces are fields named after their type. Of the fields with
+// the same name, the shallowest wins; if there are several, the only tagged
+// one wins, otherwise they are all dropped. The shallowest map field tagged
+// inline is returned separately; it holds the keys that do not match any
+// other field.
+func structFields(theType reflect.Type, options *gocoding.Options, encoding bool) (fields []*structField, inline *structField) {
+	unexported := encoding && options.Unexported
+	streamable := IsDecodableStream
+	if encoding {
+		streamable = IsEncodableStream
+	}
+
+	type embedded struct {
+		reflect.Type
+		index []int
+	}
+
+	current := []embedded{}
+	next := []embedded{{theType, nil}}
+
+	// types found at the current and next depths, and types already expanded
+	count := map[reflect.Type]int{}
+	nextCount := map[reflect.Type]int{theType: 1}
+	visited := map[reflect.Type]bool{}
+
+	candidates := []*structField{}
+
+	for len(next) > 0 {
+		current, next = next, current[:0]
+		count, nextCount = nextCount, map[reflect.Type]int{}
+
+		for _, aType := range current {
+			if visited[aType.Type] {
+				continue
+			}
+			visited[aType.Type] = true
+
+			for i := 0; i < aType.NumField(); i++ {
+				sf := aType.Field(i)
+				tag := gocoding.ParseTag(sf)
+
+				index := make([]int, len(aType.index)+1)
+				copy(index, aType.index)
+				index[len(aType.index)] = i
+
+				fieldType := sf.Type
+				if sf.Anonymous && fieldType.Kind() == reflect.Ptr {
+					fieldType = fieldType.Elem()
+				}
+
+				// skip unexported fields, except embedded structs, which
+				// may have exported fields
+				if sf.PkgPath != "" && !unexported && !(sf.Anonymous && fieldType.Kind() == reflect.Struct) {
+					continue
+				}
+
+				// skip unsupported fields, if configured
+				if options.Unsupported == gocoding.UnsupportedSkip && gocoding.IsUnsupported(sf.Type.Kind()) && !streamable(sf.Type) {
+					continue
+				}
+
+				// keep the inline map & skip
+				if tag.Has("inline") && sf.Type.Kind() == reflect.Map && sf.Type.Key().Kind() == reflect.String {
+					if inline == nil {
+						inline = &structField{sf.Name, index, sf, tag}
+					}
+					continue
+				}
+
+				// add embedded structs to the next depth & skip
+				if sf.Anonymous && fieldType.Kind() == reflect.Struct && tag.Name == "" {
+					nextCount[fieldType]++
+					if nextCount[fieldType] == 1 {
+						next = append(next, embedded{fieldType, index})
+					}
+					continue
+				}
+
+				name := options.Naming.Name(sf.Name)
+				if tag.Name != "" {
+					name = tag.Name
+				}
+
+				field := &structField{name, index, sf, tag}
+				candidates = append(candidates, field)
+
+				// a type embedded more than once conflicts with itself
+				if count[aType.Type] > 1 {
+					candidates = append(candidates, field)
+				}
+			}
+		}
+	}
+
+	// group the candidates by name, shallowest and tagged first
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.name != b.name {
+			return a.name < b.name
+		}
+		if len(a.index) != len(b.index) {
+			return len(a.index) < len(b.index)
+		}
+		return a.tag.Name != "" && b.tag.Name == ""
+	})
+
+	for i := 0; i < len(candidates); {
+		j := i + 1
+		for j < len(candidates) && candidates[j].name == candidates[i].name {
+			j++
+		}
+
+		if field, ok := dominantField(candidates[i:j]); ok {
+			fields = append(fields, field)
+		}
+		i = j
+	}
+
+	sort.Slice(fields, func(i, j int) bool {
+		a, b := fields[i].index, fields[j].index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+
+	return fields, inline
+}
+
+// dominantField picks the field that wins among fields with the same name,
+// sorted shallowest and tagged first
+func dominantField(fields []*structField) (*structField, bool) {
+	if len(fields) > 1 && len(fields[0].index) == len(fields[1].index) && (fields[0].tag.Name != "") == (fields[1].tag.Name != "") {
+		return nil, false
+	}
+	return fields[0], true
+}
+
+// fieldByIndex returns a field of a struct value, or false if it is promoted
+// through a nil embedded pointer
+func fieldByIndex(value reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				return value, false
+			}
+			value = value.Elem()
+		}
+		value = value.Field(x)
+	}
+	return value, true
+}
+
+// reservedKeys returns the keys that the marshaller writes itself, the ids of
+// references and the discriminator of the type registry, which inline maps
+// neither collect nor write
+func reservedKeys(options *gocoding.Options) []string {
+	keys := []string{gocoding.IDKey, gocoding.RefKey}
+	if options.Types != nil {
+		keys = append(keys, options.Types.Key)
+	}
+	return keys
+}
+
+// readable returns a copy of the value of an unexported field that can be
+// encoded like the value of an exported field; it is a copy so that encoders,
+// and hooks with pointer receivers, cannot modify the unexported state of the
+// struct. Scalars are read with the accessors of reflect, which work on
+// unexported fields; other kinds are read with readUnexported.
+func readable(value reflect.Value) reflect.Value {
+	if value.CanInterface() {
+		return value
+	}
+
+	copied := reflect.New(value.Type()).Elem()
+	switch value.Kind() {
+	case reflect.Bool:
+		copied.SetBool(value.Bool())
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		copied.SetInt(value.Int())
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		copied.SetUint(value.Uint())
+
+	case reflect.Float32, reflect.Float64:
+		copied.SetFloat(value.Float())
+
+	case reflect.Complex64, reflect.Complex128:
+		copied.SetComplex(value.Complex())
+
+	case reflect.String:
+		copied.SetString(value.String())
+
+	default:
+		copied.Set(readUnexported(value))
+	}
+	return copied
+}
+
+// readsUnexported returns true if readable reads unexported fields of a kind
+// with readUnexported, so that they must be addressable
+func readsUnexported(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return false
+	}
+	return true
+}
+
+// readUnexported reads an unexported field that reflect will not read, such as
+// a slice or a pointer, through package unsafe; this is the only use of unsafe
+// in the package. The field must be addressable.
+func readUnexported(value reflect.Value) reflect.Value {
+	return reflect.NewAt(value.Type(), unsafe.Pointer(value.UnsafeAddr())).Elem()
+}
+
+// fieldByIndexAlloc returns a field of a struct value, allocating nil embedded
+// pointers, or false if a pointer cannot be allocated because it is unexported
+func fieldByIndexAlloc(value reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				if !value.CanSet() {
+					return value, false
+				}
+				value.Set(reflect.New(value.Type().Elem()))
+			}
+			value = value.Elem()
+		}
+		value = value.Field(x)
+	}
+	return value, true
+}
+
+// defaultLiteral interprets the value of a default tag option as a literal of
+// the kind the field expects, falling back to a string, so that it can be
+// decoded like a scanned value
+func defaultLiteral(theType reflect.Type, str string) reflect.Value {
+	for theType.Kind() == reflect.Ptr {
+		theType = theType.Elem()
+	}
+
+	if gocoding.IsOptional(theType) {
+		field, _ := theType.FieldByName("Value")
+		return defaultLiteral(field.Type, str)
+	}
+
+	switch theType.Kind() {
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(str); err == nil {
+			return reflect.ValueOf(b)
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, err := strconv.ParseInt(str, 10, 64); err == nil {
+			return reflect.ValueOf(n)
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n, err := strconv.ParseUint(str, 10, 64); err == nil {
+			return reflect.ValueOf(n)
+		}
+
+	case reflect.Float32, reflect.Float64:
+		if f, err := strconv.ParseFloat(str, 64); err == nil {
+			return reflect.ValueOf(f)
+		}
+	}
+
+	return reflect.ValueOf(str)
+}
@@ -0,0 +1,151 @@
+package text
+
+import (
+	"fmt"
+	"github.com/FactomProject/gocoding"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// IsSetType returns true if a map type can be written as a set: its values
+// are bool or struct{}
+func IsSetType(theType reflect.Type) bool {
+	if theType.Kind() != reflect.Map {
+		return false
+	}
+
+	elem := theType.Elem()
+	return elem.Kind() == reflect.Bool || elem.Kind() == reflect.Struct && elem.NumField() == 0
+}
+
+// setFieldEncoder returns an encoder for a set field with the set tag option,
+// or nil
+func setFieldEncoder(marshaller gocoding.Marshaller, field *structField) gocoding.Encoder {
+	if !field.tag.Has("set") || !IsSetType(field.field.Type) {
+		return nil
+	}
+
+	return SetEncoding(marshaller, field.field.Type)
+}
+
+// setFieldDecoder returns a decoder for a set field with the set tag option,
+// or nil
+func setFieldDecoder(unmarshaller gocoding.Unmarshaller, field *structField) gocoding.Decoder {
+	if !field.tag.Has("set") || !IsSetType(field.field.Type) {
+		return nil
+	}
+
+	return SetDecoding(unmarshaller, field.field.Type)
+}
+
+// SetEncoding writes the members of a set as a sorted array of keys; the keys
+// of a map[K]bool that are false are not members
+func SetEncoding(marshaller gocoding.Marshaller, theType reflect.Type) gocoding.Encoder {
+	encoder := marshaller.FindEncoder(theType.Key())
+	if encoder == nil {
+		return nil
+	}
+
+	boolean := theType.Elem().Kind() == reflect.Bool
+
+	return func(scratch [64]byte, renderer gocoding.Renderer, value reflect.Value) {
+		if value.IsNil() {
+			renderer.WriteNil()
+			return
+		}
+
+		keys := make([]reflect.Value, 0, value.Len())
+		for _, key := range value.MapKeys() {
+			if !boolean || value.MapIndex(key).Bool() {
+				keys = append(keys, key)
+			}
+		}
+		sort.Slice(keys, func(i, j int) bool { return lessKey(keys[i], keys[j]) })
+
+		renderer.StartArray()
+		for i, key := range keys {
+			id := strconv.Itoa(i)
+			renderer.StartElement(id)
+			encoder(scratch, renderer, key)
+			renderer.StopElement(id)
+		}
+		renderer.StopArray()
+	}
+}
+
+func lessKey(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.String:
+		return a.String() < b.String()
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() < b.Uint()
+
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+
+	default:
+		return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+	}
+}
+
+// SetDecoding reads the members of a set from an array of keys; objects are
+// decoded as maps
+func SetDecoding(unmarshaller gocoding.Unmarshaller, theType reflect.Type) gocoding.Decoder {
+	decoder := unmarshaller.FindDecoder(theType.Key())
+	if decoder == nil {
+		return nil
+	}
+
+	var member reflect.Value
+	if theType.Elem().Kind() == reflect.Bool {
+		member = reflect.ValueOf(true).Convert(theType.Elem())
+	} else {
+		member = reflect.Zero(theType.Elem())
+	}
+
+	deep := gocoding.OptionsOf(unmarshaller).DecodeMode == gocoding.DecodeDeepMerge
+	mapDecoder := mapDecoding(unmarshaller, theType)
+
+	return func(scratch [64]byte, scanner gocoding.Scanner, value reflect.Value) {
+		if scanner.Peek().Matches(gocoding.ScannedStructBegin, gocoding.ScannedMapBegin) {
+			mapDecoder(scratch, scanner, value)
+			return
+		}
+
+		if scanner.Peek() == gocoding.ScannedLiteralBegin {
+			null := scanner.NextValue()
+			if null.IsValid() && null.IsNil() {
+				value.Set(reflect.Zero(theType))
+				return
+			}
+		}
+
+		if !gocoding.PeekCheck(scanner, gocoding.ScannedArrayBegin) {
+			return
+		}
+
+		// a set is replaced, unless it is deep merged
+		if value.IsNil() || !deep {
+			value.Set(reflect.MakeMap(theType))
+		}
+
+		for i := 0; true; i++ {
+			// get the next code, check for the end
+			code := scanner.Continue()
+			if code.Matches(gocoding.ScannedArrayEnd) {
+				break
+			}
+
+			key := reflect.New(theType.Key()).Elem()
+			gocoding.EnterIndex(scanner, i)
+			decoder(scratch, scanner, key)
+			gocoding.ExitElement(scanner)
+			value.SetMapIndex(key, member)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package text
+
+import (
+	"github.com/FactomProject/gocoding"
+	"io"
+	"reflect"
+	"strconv"
+)
+
+var readerType = reflect.TypeOf(new(io.Reader)).Elem()
+var writerType = reflect.TypeOf(new(io.Writer)).Elem()
+
+// ReaderEncoding writes the data read from an io.Reader as a string, using
+// the marshaller's byte encoding; with renderers that are StringRenderers, the
+// data is encoded straight to the renderer as it is read. The reader is
+// drained, but not closed.
+func ReaderEncoding(marshaller gocoding.Marshaller, theType reflect.Type) gocoding.Encoder {
+	byteEncoding := gocoding.OptionsOf(marshaller).ByteEncoding
+
+	return func(scratch [64]byte, renderer gocoding.Renderer, value reflect.Value) {
+		if value.IsNil() {
+			renderer.WriteNil()
+			return
+		}
+
+		str := gocoding.RenderString(renderer)
+		encoder := byteEncoding.NewEncoder(str)
+		_, err := io.Copy(encoder, value.Interface().(io.Reader))
+		if err == nil {
+			err = encoder.Close()
+		}
+		if err == nil {
+			err = str.Close()
+		}
+		if err != nil {
+			renderer.Error(gocoding.ErrorPrint("Encoding", "Reading ", GVTS(value), ": ", err.Error()))
+		}
+	}
+}
+
+// WriterDecoding decodes a byte string with the unmarshaller's byte encoding
+// and writes the data to the io.Writer held by the value; scanners that are
+// StringStreamers stream the string, so it is never held in memory. Null
+// leaves the writer untouched.
+func WriterDecoding(unmarshaller gocoding.Unmarshaller, theType reflect.Type) gocoding.Decoder {
+	byteEncoding := gocoding.OptionsOf(unmarshaller).ByteEncoding
+
+	return func(scratch [64]byte, scanner gocoding.Scanner, value reflect.Value) {
+		if value.IsNil() {
+			text := scanner.NextValue()
+			if text.IsValid() && !(text.Kind() == reflect.Interface && text.IsNil()) {
+				scanner.Error(gocoding.ErrorPrintf("Decoding", "Cannot stream into a nil %s", GTTS(theType)))
+			}
+			return
+		}
+
+		decoder := byteEncoding.NewDecoder(value.Interface().(io.Writer))
+		streamed, err := gocoding.StreamString(scanner, decoder)
+		if !streamed {
+			text := scanner.NextValue()
+			switch {
+			case !text.IsValid(), text.Kind() == reflect.Interface && text.IsNil():
+				return
+
+			case text.Kind() != reflect.String:
+				scanner.Error(gocoding.ErrorPrintf("Decoding", "Decoding %s: expected String, got %s", GTTS(theType), GVTS(text)))
+				return
+			}
+			_, err = decoder.Write([]byte(text.String()))
+		}
+		if err == nil {
+			err = decoder.Close()
+		}
+		errorCheck(scanner, err)
+	}
+}
+
+// IsSeqType returns true if a type is an iterator function, like iter.Seq
+func IsSeqType(theType reflect.Type) bool {
+	if theType.Kind() != reflect.Func || theType.NumIn() != 1 || theType.NumOut() != 0 {
+		return false
+	}
+
+	yield := theType.In(0)
+	return yield.Kind() == reflect.Func && yield.NumIn() == 1 && yield.NumOut() == 1 && yield.Out(0).Kind() == reflect.Bool
+}
+
+// IsEncodableStream returns true if values of a type are encoded as streamed
+// arrays: receive-only channels and iterator functions. Bidirectional channels
+// are not streamed, since it is not clear which side owns them.
+func IsEncodableStream(theType reflect.Type) bool {
+	return theType.Kind() == reflect.Chan && theType.ChanDir() == reflect.RecvDir || IsSeqType(theType)
+}
+
+// IsDecodableStream returns true if values of a type are decoded from streamed
+// arrays: send-only channels
+func IsDecodableStream(theType reflect.Type) bool {
+	return theType.Kind() == reflect.Chan && theType.ChanDir() == reflect.SendDir
+}
+
+// ChanEncoding writes the values received from a receive-only channel as an
+// array, each as it arrives, until the channel is closed
+func ChanEncoding(marshaller gocoding.Marshaller, theType reflect.Type) gocoding.Encoder {
+	if theType.ChanDir() != reflect.RecvDir {
+		return UnsupportedEncoding(marshaller, theType)
+	}
+
+	return streamEncoding(marshaller, theType.Elem())
+}
+
+// SeqEncoding writes the values yielded by an iterator function as an array,
+// each as it is yielded
+func SeqEncoding(marshaller gocoding.Marshaller, theType reflect.Type) gocoding.Encoder {
+	return streamEncoding(marshaller, theType.In(0).In(0))
+}
+
+func streamEncoding(marshaller gocoding.Marshaller, elemType reflect.Type) gocoding.Encoder {
+	encoder := marshaller.FindEncoder(elemType)
+	if encoder == nil {
+		return nil
+	}
+
+	return func(scratch [64]byte, renderer gocoding.Renderer, value reflect.Value) {
+		if value.IsNil() {
+			renderer.WriteNil()
+			return
+		}
+
+		renderer.StartArray()
+		i := 0
+		for elem := range value.Seq() {
+			id := strconv.Itoa(i)
+			renderer.StartElement(id)
+			encoder(scratch, renderer, elem)
+			renderer.StopElement(id)
+			i++
+		}
+		renderer.StopArray()
+	}
+}
+
+// ChanDecoding sends the elements of an array to the send-only channel held by
+// the value, each as it is decoded, and closes the channel at the end of the
+// array, or when decoding fails; null closes the channel without sending
+// anything, and leaves a nil channel nil
+func ChanDecoding(unmarshaller gocoding.Unmarshaller, theType reflect.Type) gocoding.Decoder {
+	if theType.ChanDir() != reflect.SendDir {
+		return UnsupportedDecoding(unmarshaller, theType)
+	}
+
+	decoder := unmarshaller.FindDecoder(theType.Elem())
+	if decoder == nil {
+		return nil
+	}
+
+	return func(scratch [64]byte, scanner gocoding.Scanner, value reflect.Value) {
+		if value.IsNil() {
+			null := scanner.NextValue()
+			if null.IsValid() && !(null.Kind() == reflect.Interface && null.IsNil()) {
+				scanner.Error(gocoding.ErrorPrintf("Decoding", "Cannot send to a nil %s", GTTS(theType)))
+			}
+			return
+		}
+		defer value.Close()
+
+		if scanner.Peek() == gocoding.ScannedLiteralBegin {
+			null := scanner.NextValue()
+			if null.IsValid() && null.IsNil() {
+				return
+			}
+		}
+
+		if !gocoding.PeekCheck(scanner, gocoding.ScannedArrayBegin) {
+			return
+		}
+
+		for i := 0; true; i++ {
+			// get the next code, check for the end
+			code := scanner.Continue()
+			if code.Matches(gocoding.ScannedArrayEnd) {
+				break
+			}
+
+			elem := reflect.New(theType.Elem()).Elem()
+			gocoding.EnterIndex(scanner, i)
+			decoder(scratch, scanner, elem)
+			gocoding.ExitElement(scanner)
+			value.Send(elem)
+		}
+	}
+}
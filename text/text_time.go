@@ -0,0 +1,251 @@
+package text
+
+import (
+	"github.com/FactomProject/gocoding"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Builtin returns true if text has its own encoder and decoder for the type (or
+// the type it points to), which take precedence over any marshalling
+// interfaces the type implements
+func Builtin(theType reflect.Type) bool {
+	if theType.Kind() == reflect.Ptr {
+		theType = theType.Elem()
+	}
+
+	return theType == timeType || theType == durationType
+}
+
+func builtinEncoding(marshaller gocoding.Marshaller, theType reflect.Type) gocoding.Encoder {
+	switch theType {
+	case timeType:
+		return TimeEncoding(gocoding.OptionsOf(marshaller).TimeFormat)
+
+	case durationType:
+		return DurationEncoding(gocoding.OptionsOf(marshaller).DurationFormat)
+	}
+
+	return PtrEncoding(marshaller, theType)
+}
+
+func builtinDecoding(unmarshaller gocoding.Unmarshaller, theType reflect.Type) gocoding.Decoder {
+	switch theType {
+	case timeType:
+		return TimeDecoding(gocoding.OptionsOf(unmarshaller).TimeFormat)
+
+	case durationType:
+		return DurationDecoding(gocoding.OptionsOf(unmarshaller).DurationFormat)
+	}
+
+	return PtrDecoding(unmarshaller, theType)
+}
+
+// timeFieldEncoder returns an encoder for a time field with a time or duration
+// tag option, or nil if the field has neither
+func timeFieldEncoder(field *structField) gocoding.Encoder {
+	theType := field.field.Type
+	if theType.Kind() == reflect.Ptr {
+		theType = theType.Elem()
+	}
+
+	var encoder gocoding.Encoder
+	if name, ok := field.tag.Get("time"); ok && theType == timeType {
+		if format, ok := gocoding.ParseTimeFormat(name); ok {
+			encoder = TimeEncoding(format)
+		} else {
+			encoder = errorEncoding(gocoding.ErrorPrint("Encoding", "Unknown time format: ", name))
+		}
+	} else if name, ok := field.tag.Get("duration"); ok && theType == durationType {
+		if format, ok := gocoding.ParseDurationFormat(name); ok {
+			encoder = DurationEncoding(format)
+		} else {
+			encoder = errorEncoding(gocoding.ErrorPrint("Encoding", "Unknown duration format: ", name))
+		}
+	} else {
+		return nil
+	}
+
+	if theType == field.field.Type {
+		return encoder
+	}
+
+	return func(scratch [64]byte, renderer gocoding.Renderer, value reflect.Value) {
+		if value.IsNil() {
+			renderer.WriteNil()
+		} else {
+			encoder(scratch, renderer, value.Elem())
+		}
+	}
+}
+
+// timeFieldDecoder returns a decoder for a time field with a time or duration
+// tag option, or nil if the field has neither
+func timeFieldDecoder(field *structField) gocoding.Decoder {
+	theType := field.field.Type
+	if theType.Kind() == reflect.Ptr {
+		theType = theType.Elem()
+	}
+
+	var decoder gocoding.Decoder
+	if name, ok := field.tag.Get("time"); ok && theType == timeType {
+		if format, ok := gocoding.ParseTimeFormat(name); ok {
+			decoder = TimeDecoding(format)
+		} else {
+			decoder = gocoding.ErrorDecoding(gocoding.ErrorPrint("Decoding", "Unknown time format: ", name))
+		}
+	} else if name, ok := field.tag.Get("duration"); ok && theType == durationType {
+		if format, ok := gocoding.ParseDurationFormat(name); ok {
+			decoder = DurationDecoding(format)
+		} else {
+			decoder = gocoding.ErrorDecoding(gocoding.ErrorPrint("Decoding", "Unknown duration format: ", name))
+		}
+	} else {
+		return nil
+	}
+
+	if theType == field.field.Type {
+		return decoder
+	}
+
+	return func(scratch [64]byte, scanner gocoding.Scanner, value reflect.Value) {
+		if value.IsNil() {
+			value.Set(reflect.New(theType))
+		}
+
+		decoder(scratch, scanner, value.Elem())
+	}
+}
+
+func TimeEncoding(format gocoding.TimeFormat) gocoding.Encoder {
+	return func(scratch [64]byte, renderer gocoding.Renderer, value reflect.Value) {
+		t := value.Interface().(time.Time)
+
+		switch format {
+		case gocoding.TimeRFC3339:
+			renderer.PrintString(t.Format(time.RFC3339))
+
+		case gocoding.TimeUnixSeconds:
+			renderer.Write(strconv.AppendInt(scratch[:0], t.Unix(), 10))
+
+		case gocoding.TimeUnixMillis:
+			renderer.Write(strconv.AppendInt(scratch[:0], t.UnixNano()/int64(time.Millisecond), 10))
+
+		default:
+			renderer.PrintString(t.Format(time.RFC3339Nano))
+		}
+	}
+}
+
+// TimeDecoding reads times written as RFC 3339 strings, or as numbers in the
+// given format; fractional numbers keep their sub-second part
+func TimeDecoding(format gocoding.TimeFormat) gocoding.Decoder {
+	return func(scratch [64]byte, scanner gocoding.Scanner, value reflect.Value) {
+		var t time.Time
+
+		scanned := scanner.NextValue()
+		switch scanned.Kind() {
+		case reflect.Invalid:
+			return
+
+		case reflect.Interface:
+			// null
+
+		case reflect.String:
+			var err error
+			t, err = time.Parse(time.RFC3339Nano, scanned.String())
+			errorCheck(scanner, err)
+
+		case reflect.Int64:
+			if format == gocoding.TimeUnixMillis {
+				t = time.Unix(0, scanned.Int()*int64(time.Millisecond))
+			} else {
+				t = time.Unix(scanned.Int(), 0)
+			}
+
+		case reflect.Float64:
+			// split the whole units off, since a float64 cannot hold a time
+			// in nanoseconds exactly
+			whole, frac := math.Modf(scanned.Float())
+			if format == gocoding.TimeUnixMillis {
+				t = time.Unix(0, int64(whole)*int64(time.Millisecond)+int64(math.Round(frac*float64(time.Millisecond))))
+			} else {
+				t = time.Unix(int64(whole), int64(math.Round(frac*float64(time.Second))))
+			}
+
+		default:
+			scanner.Error(gocoding.ErrorPrintf("Decoding", "Scanned %s while unmarshalling %s", GVTS(scanned), GVTS(value)))
+			return
+		}
+
+		value.Set(reflect.ValueOf(t))
+	}
+}
+
+func DurationEncoding(format gocoding.DurationFormat) gocoding.Encoder {
+	return func(scratch [64]byte, renderer gocoding.Renderer, value reflect.Value) {
+		d := time.Duration(value.Int())
+
+		switch format {
+		case gocoding.DurationString:
+			renderer.PrintString(d.String())
+
+		case gocoding.DurationMilliseconds:
+			renderer.Write(strconv.AppendInt(scratch[:0], int64(d/time.Millisecond), 10))
+
+		case gocoding.DurationSeconds:
+			renderer.Write(strconv.AppendInt(scratch[:0], int64(d/time.Second), 10))
+
+		default:
+			renderer.Write(strconv.AppendInt(scratch[:0], int64(d), 10))
+		}
+	}
+}
+
+// DurationDecoding reads durations written as strings, or as numbers in the
+// units of the given format
+func DurationDecoding(format gocoding.DurationFormat) gocoding.Decoder {
+	unit := time.Nanosecond
+	switch format {
+	case gocoding.DurationMilliseconds:
+		unit = time.Millisecond
+
+	case gocoding.DurationSeconds:
+		unit = time.Second
+	}
+
+	return func(scratch [64]byte, scanner gocoding.Scanner, value reflect.Value) {
+		var d time.Duration
+
+		scanned := scanner.NextValue()
+		switch scanned.Kind() {
+		case reflect.Invalid:
+			return
+
+		case reflect.Interface:
+			// null
+
+		case reflect.String:
+			var err error
+			d, err = time.ParseDuration(scanned.String())
+			errorCheck(scanner, err)
+
+		case reflect.Int64:
+			d = time.Duration(scanned.Int()) * unit
+
+		case reflect.Float64:
+			d = time.Duration(scanned.Float() * float64(unit))
+
+		default:
+			scanner.Error(gocoding.ErrorPrintf("Decoding", "Scanned %s while unmarshalling %s", GVTS(scanned), GVTS(value)))
+			return
+		}
+
+		value.SetInt(int64(d))
+	}
+}
@@ -0,0 +1,87 @@
+package gocoding
+
+import (
+	"reflect"
+	"sync"
+)
+
+// TypeRegistry names the concrete types that may be stored in interface
+// values. When a registry is set with WithTypeRegistry, the marshaller writes
+// the name of a registered type under the discriminator key of the object
+// encoded for an interface value, and the unmarshaller reads it back to choose
+// the type to decode into. Objects without a discriminator are decoded as if
+// there were no registry; a discriminator that names no registered type is an
+// error.
+type TypeRegistry struct {
+	Key string
+
+	sync.RWMutex
+	names map[reflect.Type]string
+	types map[string]reflect.Type
+}
+
+func NewTypeRegistry(key string) *TypeRegistry {
+	return &TypeRegistry{Key: key, names: make(map[reflect.Type]string), types: make(map[string]reflect.Type)}
+}
+
+// Register names the type of obj, which may be a pointer
+func (r *TypeRegistry) Register(name string, obj interface{}) {
+	r.RegisterType(name, reflect.TypeOf(obj))
+}
+
+func (r *TypeRegistry) RegisterType(name string, theType reflect.Type) {
+	r.Lock()
+	r.names[theType] = name
+	r.types[name] = theType
+	r.Unlock()
+}
+
+// Name returns the name of a registered type
+func (r *TypeRegistry) Name(theType reflect.Type) (name string, ok bool) {
+	r.RLock()
+	name, ok = r.names[theType]
+	r.RUnlock()
+	return
+}
+
+// Type returns the registered type with the given name
+func (r *TypeRegistry) Type(name string) (theType reflect.Type, ok bool) {
+	r.RLock()
+	theType, ok = r.types[name]
+	r.RUnlock()
+	return
+}
+
+// Discriminator returns the string under the discriminator key of a scanned
+// object, or false if it has none
+func (r *TypeRegistry) Discriminator(obj reflect.Value) (string, bool) {
+	if obj.Kind() != reflect.Map {
+		return "", false
+	}
+
+	name := obj.MapIndex(reflect.ValueOf(r.Key))
+	if !name.IsValid() {
+		return "", false
+	}
+
+	if name.Kind() == reflect.Interface {
+		name = name.Elem()
+	}
+
+	if name.Kind() != reflect.String {
+		return "", false
+	}
+
+	return name.String(), true
+}
+
+// Discriminate returns the registered type named by the discriminator key of a
+// scanned object
+func (r *TypeRegistry) Discriminate(obj reflect.Value) (reflect.Type, bool) {
+	name, ok := r.Discriminator(obj)
+	if !ok {
+		return nil, false
+	}
+
+	return r.Type(name)
+}
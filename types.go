@@ -24,6 +24,7 @@ type Marshaller interface {
 	FindEncoder(reflect.Type) Encoder
 	IsCached(reflect.Type) bool
 	CacheEncoder(reflect.Type, Encoder)
+	RegisterEncoder(reflect.Type, Encoder)
 }
 
 type Encoder func([64]byte, Renderer, reflect.Value)
@@ -37,6 +38,12 @@ type Encodable2 interface {
 	EncodableFields() map[string]reflect.Value
 }
 
+// Encodable3 is an ordered Encodable2, whose fields can have options and
+// encoders
+type Encodable3 interface {
+	EncodableFieldList() []Field
+}
+
 type Renderer interface {
 	Errorable
 	io.Writer
@@ -65,6 +72,7 @@ type Unmarshaller interface {
 	UnmarshalValue(Scanner, reflect.Value)
 	FindDecoder(reflect.Type) Decoder
 	CacheDecoder(reflect.Type, Decoder)
+	RegisterDecoder(reflect.Type, Decoder)
 }
 
 type Decoder func([64]byte, Scanner, reflect.Value)
@@ -78,6 +86,20 @@ type Decodable2 interface {
 	DecodableFields() map[string]reflect.Value
 }
 
+// FieldDecodable is implemented by structs that decode some keys themselves.
+// The struct decoder calls DecodeField with the scanner at the value of each
+// key; if it returns false, it must not have scanned the value, and the key is
+// matched against the fields of the struct.
+type FieldDecodable interface {
+	DecodeField(key string, unmarshaller Unmarshaller, scanner Scanner) bool
+}
+
+// Decodable3 is an ordered Decodable2, whose fields can have options and
+// decoders
+type Decodable3 interface {
+	DecodableFieldList() []Field
+}
+
 type Scanner interface {
 	Errorable
 
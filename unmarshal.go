@@ -5,15 +5,17 @@ import (
 	"sync"
 )
 
-func NewUnmarshaller(decoding Decoding) Unmarshaller {
-	return &unmarshaller{decoding: decoding, cache: make(map[reflect.Type]Decoder)}
+func NewUnmarshaller(decoding Decoding, options ...Option) Unmarshaller {
+	return &unmarshaller{decoding: decoding, options: newOptions(options), cache: make(map[reflect.Type]Decoder), registry: make(map[reflect.Type]Decoder)}
 }
 
 type unmarshaller struct {
 	decoding Decoding
+	options  *Options
 
 	sync.RWMutex
-	cache map[reflect.Type]Decoder
+	cache    map[reflect.Type]Decoder
+	registry map[reflect.Type]Decoder
 
 	scratch [64]byte
 }
@@ -25,11 +27,31 @@ func (u *unmarshaller) Unmarshal(scanner Scanner, obj interface{}) (err error) {
 		}
 	}()
 
+	session := &unmarshalSession{}
+	if u.options.References == ReferencesPreserve {
+		session.references = make(map[string]reflect.Value)
+	}
+
+	if u.options.DecodeMode == DecodeReplace {
+		value := reflect.ValueOf(obj)
+		if value.Kind() == reflect.Ptr && !value.IsNil() {
+			value.Elem().Set(reflect.Zero(value.Type().Elem()))
+		}
+	}
+
 	scanner.Continue()
-	u.UnmarshalObject(scanner, obj)
+	u.UnmarshalObject(&sessionScanner{scanner, session}, obj)
+
+	if len(session.errors) > 0 {
+		err = session.errors
+	}
 	return
 }
 
+func (u *unmarshaller) Options() *Options {
+	return u.options
+}
+
 func (u *unmarshaller) UnmarshalObject(scanner Scanner, obj interface{}) {
 	u.UnmarshalValue(scanner, reflect.ValueOf(obj))
 }
@@ -47,11 +69,19 @@ func (u *unmarshaller) FindDecoder(theType reflect.Type) (decoder Decoder) {
 	// check the cache
 	u.RLock()
 	decoder = u.cache[theType]
+	registered := u.registry[theType]
 	u.RUnlock()
 	if decoder != nil {
 		return decoder
 	}
 
+	// registered decoders override the decoding
+	if registered != nil {
+		decoder = lifecycleDecoder(theType, registered)
+		u.CacheDecoder(theType, decoder)
+		return decoder
+	}
+
 	switch theType.Kind() {
 	case reflect.Array, reflect.Interface, reflect.Map, reflect.Slice, reflect.Struct, reflect.Ptr:
 		decoder = u.recurseSafeFindAndCacheDecoder(theType)
@@ -62,10 +92,11 @@ func (u *unmarshaller) FindDecoder(theType reflect.Type) (decoder Decoder) {
 		/*reflect.Complex64, reflect.Complex128,*/
 		reflect.Float32, reflect.Float64:
 		// simple types don't need locking
-		decoder = u.decoding(u, theType)
+		decoder = lifecycleDecoder(theType, u.decoding(u, theType))
 
 	default:
-		panic(ErrorPrint("Decoding", "Unsupported type: ", theType))
+		// unsupported types are handled according to Options.Unsupported
+		decoder = u.decoding(u, theType)
 	}
 
 	u.CacheDecoder(theType, decoder)
@@ -75,6 +106,7 @@ func (u *unmarshaller) FindDecoder(theType reflect.Type) (decoder Decoder) {
 
 var decodableType1 = reflect.TypeOf(new(Decodable1)).Elem()
 var decodableType2 = reflect.TypeOf(new(Decodable2)).Elem()
+var decodableType3 = reflect.TypeOf(new(Decodable3)).Elem()
 
 func (u *unmarshaller) recurseSafeFindAndCacheDecoder(theType reflect.Type) (decoder Decoder) {
 	// to deal with recursive types, create an indirect decoder
@@ -96,22 +128,34 @@ func (u *unmarshaller) recurseSafeFindAndCacheDecoder(theType reflect.Type) (dec
 	return
 }
 
-func (u *unmarshaller) checkDecodable(theType reflect.Type) Decoder {
-	if theType.ConvertibleTo(decodableType1) {
-		return reflect.New(theType).Elem().Interface().(Decodable1).Decoding(u, theType)
+// checkDecodable returns a decoder for a type that implements one of the
+// Decodable interfaces with the receiver, or nil; the decoder of a pointer
+// receiver decodes pointers to the type
+func (u *unmarshaller) checkDecodable(theType reflect.Type, receiver Receiver) Decoder {
+	decodable := theType
+	if receiver == PointerReceiver {
+		decodable = reflect.PtrTo(theType)
 	}
 
-	if theType.ConvertibleTo(decodableType2) {
-		return Decodable2Decoding(u, theType)
+	if Implements(theType, decodableType1) == receiver {
+		return reflect.New(decodable).Elem().Interface().(Decodable1).Decoding(u, decodable)
+	}
+
+	if Implements(theType, decodableType3) == receiver {
+		return Decodable3Decoding(u, decodable)
+	}
+
+	if Implements(theType, decodableType2) == receiver {
+		return Decodable2Decoding(u, decodable)
 	}
 
 	return nil
 }
 
 func (u *unmarshaller) recurseUnsafeFindDecoder(theType reflect.Type) Decoder {
-	decoder := u.checkDecodable(theType)
+	decoder := u.checkDecodable(theType, ValueReceiver)
 	if decoder != nil {
-		return decoder
+		return lifecycleDecoder(theType, decoder)
 	}
 
 	decoder = u.decoding(u, theType)
@@ -122,17 +166,12 @@ func (u *unmarshaller) recurseUnsafeFindDecoder(theType reflect.Type) Decoder {
 		}
 	}
 
-	if theType.Kind() == reflect.Ptr {
-		return decoder
-	}
-
-	indirect := u.checkDecodable(reflect.PtrTo(theType))
-
-	if indirect != nil {
-		decoder = TryIndirectDecoding(decoder, indirect)
+	// decode addressable values with the pointer's hooks
+	if indirect := u.checkDecodable(theType, PointerReceiver); indirect != nil {
+		decoder = HookDecoder(PointerReceiver, indirect, decoder)
 	}
 
-	return decoder
+	return lifecycleDecoder(theType, decoder)
 }
 
 func (u *unmarshaller) IsCached(theType reflect.Type) bool {
@@ -145,3 +184,13 @@ func (u *unmarshaller) CacheDecoder(theType reflect.Type, decoder Decoder) {
 	u.cache[theType] = decoder
 	u.Unlock()
 }
+
+// RegisterDecoder sets the decoder for a type, overriding the unmarshaller's
+// decoding. Since decoders for other types may have already found the old
+// decoder, registering clears the cache.
+func (u *unmarshaller) RegisterDecoder(theType reflect.Type, decoder Decoder) {
+	u.Lock()
+	u.registry[theType] = decoder
+	u.cache = make(map[reflect.Type]Decoder)
+	u.Unlock()
+}
@@ -0,0 +1,219 @@
+package gocoding
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ScanValue returns a Scanner that walks an already scanned value, such as the
+// map[string]interface{} returned by Scanner.NextValue(), so that it can be
+// decoded again. Maps (with string keys), slices, arrays, pointers, interfaces
+// and literals are supported.
+func ScanValue(obj interface{}) Scanner {
+	s := &valueScanner{stack: make([]ScannerCode, 0, 5)}
+	s.tokenize(reflect.ValueOf(obj))
+	return s
+}
+
+type valueToken struct {
+	code  ScannerCode
+	value reflect.Value
+	end   int // index of the matching end token
+}
+
+type valueScanner struct {
+	BasicErrorable
+
+	stack  []ScannerCode
+	tokens []valueToken
+	cursor int
+}
+
+var interType = reflect.TypeOf(new(interface{})).Elem()
+
+func (s *valueScanner) tokenize(value reflect.Value) {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			break
+		}
+		value = value.Elem()
+	}
+
+	begin := len(s.tokens)
+
+	switch value.Kind() {
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			s.Error(ErrorPrint("Scanner", "Unsupported map key type: ", value.Type().Key()))
+			return
+		}
+
+		keys := value.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+		s.tokens = append(s.tokens, valueToken{code: ScannedStructBegin, value: value})
+		for _, key := range keys {
+			s.tokens = append(s.tokens, valueToken{code: ScannedKeyBegin, value: reflect.ValueOf(key.String())})
+			s.tokens = append(s.tokens, valueToken{code: ScannedKeyEnd})
+			s.tokenize(value.MapIndex(key))
+		}
+		s.tokens = append(s.tokens, valueToken{code: ScannedStructEnd})
+
+	case reflect.Slice, reflect.Array:
+		s.tokens = append(s.tokens, valueToken{code: ScannedArrayBegin, value: value})
+		for i := 0; i < value.Len(); i++ {
+			s.tokenize(value.Index(i))
+		}
+		s.tokens = append(s.tokens, valueToken{code: ScannedArrayEnd})
+
+	default:
+		s.tokens = append(s.tokens, valueToken{code: ScannedLiteralBegin, value: literal(value)})
+		s.tokens = append(s.tokens, valueToken{code: ScannedLiteralEnd})
+	}
+
+	s.tokens[begin].end = len(s.tokens) - 1
+}
+
+// normalize a literal to the types produced by scanning text
+func literal(value reflect.Value) reflect.Value {
+	switch value.Kind() {
+	case reflect.Invalid, reflect.Ptr, reflect.Interface:
+		return reflect.Zero(interType)
+
+	case reflect.Bool:
+		return reflect.ValueOf(value.Bool())
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflect.ValueOf(value.Int())
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return reflect.ValueOf(int64(value.Uint()))
+
+	case reflect.Float32, reflect.Float64:
+		return reflect.ValueOf(value.Float())
+
+	case reflect.String:
+		return reflect.ValueOf(value.String())
+
+	default:
+		return reflect.ValueOf(fmt.Sprint(value.Interface()))
+	}
+}
+
+func (s *valueScanner) Peek() ScannerCode {
+	if len(s.stack) == 0 {
+		return ScannerBadCode
+	}
+
+	return s.stack[len(s.stack)-1]
+}
+
+func (s *valueScanner) NextCode() ScannerCode {
+	if s.cursor >= len(s.tokens) {
+		return ScannedToEnd
+	}
+
+	code := s.tokens[s.cursor].code
+	s.cursor++
+
+	last := len(s.stack) - 1
+	switch code {
+	case ScannedKeyBegin:
+		s.stack = append(s.stack, code)
+
+	case ScannedKeyEnd:
+		s.stack[last] = code
+
+	case ScannedLiteralBegin, ScannedStructBegin, ScannedArrayBegin:
+		if last >= 0 && s.stack[last] == ScannedKeyEnd {
+			s.stack[last] = code
+		} else {
+			s.stack = append(s.stack, code)
+		}
+
+	case ScannedLiteralEnd, ScannedStructEnd, ScannedArrayEnd:
+		s.stack = s.stack[:last]
+	}
+
+	return code
+}
+
+func (s *valueScanner) Continue() ScannerCode {
+	return s.NextCode()
+}
+
+func (s *valueScanner) NextValue() reflect.Value {
+	// make sure there's a begin code on the stack
+	if len(s.stack) == 0 && s.NextCode() == ScannedToEnd {
+		return reflect.ValueOf(nil)
+	}
+
+	token := s.tokens[s.cursor-1]
+
+	switch code := s.Peek(); code {
+	case ScannedKeyBegin, ScannedLiteralBegin:
+		s.NextCode()
+		return token.value
+
+	case ScannedStructBegin, ScannedArrayBegin:
+		for s.cursor <= token.end {
+			s.NextCode()
+		}
+		return generic(token.value)
+
+	default:
+		s.Error(ErrorPrintf("Scanner", "Scanning: unexpected code %s", code.String()))
+		return reflect.ValueOf(nil)
+	}
+}
+
+// NextString returns the next value as JSON text, the form the scanned value
+// had if it was scanned from JSON, so that decoders that read raw text, such
+// as json.Unmarshaler, can decode it
+func (s *valueScanner) NextString() string {
+	value := s.NextValue()
+	if !value.IsValid() {
+		return "null"
+	}
+
+	buf := new(bytes.Buffer)
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value.Interface()); err != nil {
+		s.Error(ErrorPrint("Scanner", "Rendering a scanned value: ", err))
+		return ""
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// convert a value to the generic types produced by scanning text
+func generic(value reflect.Value) reflect.Value {
+	switch value.Kind() {
+	case reflect.Map:
+		mapv := reflect.MakeMap(reflect.TypeOf(map[string]interface{}{}))
+		for _, key := range value.MapKeys() {
+			mapv.SetMapIndex(reflect.ValueOf(key.String()), generic(value.MapIndex(key)))
+		}
+		return mapv
+
+	case reflect.Slice, reflect.Array:
+		array := reflect.MakeSlice(reflect.TypeOf([]interface{}{}), 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			array = reflect.Append(array, generic(value.Index(i)))
+		}
+		return array
+
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			return reflect.Zero(interType)
+		}
+		return generic(value.Elem())
+
+	default:
+		return literal(value)
+	}
+}